@@ -3,6 +3,7 @@ package files
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 
 	"github.com/achwo/narr/utils"
 
@@ -15,6 +16,14 @@ var listCmd = &cobra.Command{
 	Example: "narr file list [path]",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		noPath, _ := cmd.Flags().GetBool("noPath")
+		collator := utils.Collator{}
+		collator.RomanNumerals, _ = cmd.Flags().GetBool("roman")
+		collator.SpelledNumbers, _ = cmd.Flags().GetBool("spelled")
+		collator.Locale, _ = cmd.Flags().GetString("locale")
+		if err := collator.Validate(); err != nil {
+			return err
+		}
+
 		path, err := utils.GetValidFullpathFromArgs(args, 0)
 		if err != nil {
 			return fmt.Errorf("could not resolve path %s: %w", args[0], err)
@@ -25,6 +34,8 @@ var listCmd = &cobra.Command{
 			return fmt.Errorf("failed to read files within %s: %w", path, err)
 		}
 
+		slices.SortFunc(files, collator.Compare)
+
 		for _, file := range files {
 			if noPath {
 				fmt.Println(filepath.Base(file))
@@ -41,4 +52,7 @@ func init() {
 	FilesCmd.AddCommand(listCmd)
 
 	listCmd.Flags().Bool("noPath", false, "Print only the file name")
+	listCmd.Flags().Bool("roman", false, "Sort roman numerals as numbers")
+	listCmd.Flags().Bool("spelled", false, "Sort spelled-out numbers (English and German) as numbers")
+	listCmd.Flags().String("locale", "", "Language of spelled-out numbers: en or de (default both)")
 }
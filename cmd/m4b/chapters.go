@@ -0,0 +1,211 @@
+package m4b
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/achwo/narr/utils"
+	"github.com/spf13/cobra"
+)
+
+var chaptersRootCmd = &cobra.Command{
+	Use:   "chapters",
+	Short: "Edit the chapters of a project",
+}
+
+var chaptersEditCmd = &cobra.Command{
+	Use:   "edit <dir>",
+	Short: "Edit the computed chapters in $EDITOR and save them as overrides",
+	Long: `Edit the computed chapters in $EDITOR and save them as overrides
+
+The chapters are opened as a table with index, start, duration and title.
+After saving, the differences to the computed chapters are written to
+chapters.overrides in narr.yaml, replacing the overrides there.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := utils.GetValidDirPathFromArgs(args, 0)
+		if err != nil {
+			return fmt.Errorf("could not resolve path: %w", err)
+		}
+
+		projects, err := loadProjects(cmd, path)
+		if err != nil {
+			return fmt.Errorf("could not create project(s): %w", err)
+		}
+
+		// the overrides are saved to narr.yaml, which --save creates for zero-config
+		configPath := filepath.Join(path, "narr.yaml")
+		if _, err = os.Stat(configPath); err != nil {
+			return fmt.Errorf("no narr.yaml in %s, rerun with --save or create one with 'narr m4b generate'", path)
+		}
+		if len(projects) != 1 {
+			return fmt.Errorf("got %d projects for path, chapters can only be edited for a single project", len(projects))
+		}
+		project := projects[0]
+
+		table, err := project.ChapterTable()
+		if err != nil {
+			return fmt.Errorf("could not get chapters: %w", err)
+		}
+
+		edited, err := editInEditor(table)
+		if err != nil {
+			return err
+		}
+		if edited == table {
+			fmt.Println("No changes")
+			return nil
+		}
+
+		overrides, err := project.OverridesFromChapterTable(edited)
+		if err != nil {
+			return fmt.Errorf("could not apply edited chapters: %w", err)
+		}
+
+		if err = m4b.SaveChapterOverrides(configPath, overrides); err != nil {
+			return err
+		}
+
+		fmt.Printf("Saved %d override(s) to %s\n", len(overrides), configPath)
+		for _, override := range overrides {
+			fmt.Println(override)
+		}
+		return nil
+	},
+}
+
+var chaptersExportCmd = &cobra.Command{
+	Use:   "export <dir|file>",
+	Short: "Export the chapters of a project or an m4b file",
+	Long: `Export the chapters of a project or an m4b file
+
+For a directory, the chapters the project would write are exported. For a
+file, the chapters embedded in the file are read with ffprobe.`,
+	Example: `narr m4b chapters export --format vtt book/ > chapters.vtt
+narr m4b chapters export --format podcast-json -o chapters.json book.m4b`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+
+		path, err := utils.GetValidFullpathFromArgs(args, 0)
+		if err != nil {
+			return fmt.Errorf("could not resolve path: %w", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		var chapters []m4b.ChapterTiming
+		var audioFile string
+
+		if info.IsDir() {
+			projects, err := loadProjects(cmd, path)
+			if err != nil {
+				return fmt.Errorf("could not create project(s): %w", err)
+			}
+			if len(projects) != 1 {
+				return fmt.Errorf("got %d projects for path, chapters can only be exported for a single project", len(projects))
+			}
+
+			chapters, err = projects[0].ChapterTimings()
+			if err != nil {
+				return fmt.Errorf("could not get chapters: %w", err)
+			}
+
+			filename, err := projects[0].Filename()
+			if err != nil {
+				return fmt.Errorf("could not get filename: %w", err)
+			}
+			audioFile = filepath.Base(filename)
+		} else {
+			chapters, err = m4b.DefaultProjectDependencies().AudioProcessor.ReadChapters(path)
+			if err != nil {
+				return err
+			}
+			if len(chapters) == 0 {
+				return fmt.Errorf("%s has no chapters", path)
+			}
+			audioFile = filepath.Base(path)
+		}
+
+		exported, err := m4b.ExportChapters(chapters, format, audioFile)
+		if err != nil {
+			return err
+		}
+
+		if output == "" {
+			fmt.Print(exported)
+			return nil
+		}
+
+		if err = os.WriteFile(output, []byte(exported), 0644); err != nil {
+			return fmt.Errorf("could not write chapters: %w", err)
+		}
+		return nil
+	},
+}
+
+// editInEditor opens content in a temporary file in $VISUAL or $EDITOR
+// (falling back to vi) and returns the saved content.
+func editInEditor(content string) (string, error) {
+	file, err := os.CreateTemp("", "narr-chapters-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("could not create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not write temporary file: %w", err)
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// the editor may come with arguments, e.g. "code --wait"
+	fields := strings.Fields(editor)
+	editorCmd := exec.Command(fields[0], append(fields[1:], file.Name())...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+
+	if err = editorCmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("editor %s exited with %d, chapters unchanged", editor, exitErr.ExitCode())
+		}
+		return "", fmt.Errorf("could not run editor %s: %w", editor, err)
+	}
+
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("could not read edited chapters: %w", err)
+	}
+	return string(edited), nil
+}
+
+func init() {
+	M4bCmd.AddCommand(chaptersRootCmd)
+	chaptersRootCmd.AddCommand(chaptersEditCmd)
+	chaptersRootCmd.AddCommand(chaptersExportCmd)
+	addSaveFlag(chaptersEditCmd)
+	addSaveFlag(chaptersExportCmd)
+
+	chaptersExportCmd.Flags().StringP("format", "f", m4b.ChapterFormatFFMetadata,
+		fmt.Sprintf("Format of the exported chapters, one of %s", strings.Join(m4b.ChapterExportFormats, ", ")))
+	chaptersExportCmd.Flags().StringP("output", "o", "", "Write the chapters to a file instead of stdout")
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/achwo/narr/m4b"
 	"github.com/achwo/narr/utils"
@@ -13,28 +14,53 @@ import (
 
 var generateCmd = &cobra.Command{
 	Use:   "generate <dir>",
-	Short: "Generate an empty config",
+	Short: "Generate a config with settings suggested from the audio files",
+	Long: `Generate a config with settings suggested from the audio files
+
+Probes the tracks and writes a narr.yaml with suggested settings. Every
+suggestion is explained by a comment, so it can be accepted or deleted.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		path, err := utils.GetValidFullpathFromArgs(args, 0)
 		if err != nil {
 			return fmt.Errorf("could not resolve path %s: %w", args[0], err)
 		}
 
-		emptyConfig := m4b.ProjectConfig{
-			CoverPath:     "",
-			HasChapters:   false,
-			MetadataRules: []m4b.MetadataRule{},
-			ChapterRules:  []m4b.ChapterRule{},
-		}
+		interactive, _ := cmd.Flags().GetBool("interactive")
 
-		jsonBytes, err := yaml.Marshal(emptyConfig)
+		deps := m4b.DefaultProjectDependencies()
+		suggestion, err := m4b.SuggestConfig(path, deps)
 		if err != nil {
-			return fmt.Errorf("could not marshal empty config, %w", err)
+			return fmt.Errorf("could not suggest config: %w", err)
+		}
+
+		var yamlBytes []byte
+		if interactive {
+			wizard, err := newWizard(os.Stdin, os.Stdout, path, suggestion, deps)
+			if err != nil {
+				return fmt.Errorf("could not start wizard: %w", err)
+			}
+
+			config, err := wizard.Run()
+			if err != nil {
+				return err
+			}
+
+			yamlBytes, err = yaml.Marshal(config)
+			if err != nil {
+				return fmt.Errorf("could not marshal config, %w", err)
+			}
+		} else {
+			yamlBytes, err = suggestion.YAML()
+			if err != nil {
+				return fmt.Errorf("could not marshal config, %w", err)
+			}
 		}
 
 		fullpath := filepath.Join(path, "narr.yaml")
 		fmt.Println("Writing config to", fullpath)
-		os.WriteFile(fullpath, jsonBytes, 0644)
+		if err = os.WriteFile(fullpath, yamlBytes, 0644); err != nil {
+			return fmt.Errorf("could not write config: %w", err)
+		}
 		return nil
 	},
 }
@@ -43,14 +69,12 @@ var checkCmd = &cobra.Command{
 	Use:   "check <dir>",
 	Short: "Check config for validity",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		recursive, _ := cmd.Flags().GetBool("recursive")
-
 		path, err := utils.GetValidFullpathFromArgs(args, 0)
 		if err != nil {
 			return fmt.Errorf("could not resolve path %s: %w", args[0], err)
 		}
 
-		projects, err := m4b.NewProjectsByArgs(path, recursive)
+		projects, err := loadProjects(cmd, path)
 
 		if err != nil {
 			return fmt.Errorf("could not create project(s): %w", err)
@@ -58,6 +82,9 @@ var checkCmd = &cobra.Command{
 
 		for _, project := range projects {
 			fmt.Printf("\n# Project %s\n", project.Config.ProjectPath)
+			if group := project.Group(); group != "" {
+				fmt.Printf("Group: %s\n", group)
+			}
 			fmt.Println("## Tracks")
 			tracks, err := project.Tracks()
 			if err != nil {
@@ -65,16 +92,53 @@ var checkCmd = &cobra.Command{
 			}
 
 			for _, track := range tracks {
-				fmt.Println(track.File)
+				if start, end, isSegment := track.Segment(); isSegment {
+					fmt.Printf("%s [%s - %s]\n", track.File, formatDuration(start), formatDuration(end))
+				} else {
+					fmt.Println(track.File)
+				}
+			}
+
+			var warnings []string
+			if err = project.ValidateOrder(); err != nil {
+				warnings = append(warnings, err.Error())
+			}
+
+			conflicts, err := project.MetadataConflicts()
+			if err != nil {
+				return fmt.Errorf("could not compare track metadata: %w", err)
+			}
+			for _, conflict := range conflicts {
+				warnings = append(warnings, fmt.Sprintf("%s, possibly multiple books in one folder", conflict))
+			}
+
+			if len(warnings) > 0 {
+				fmt.Println("\n## Warnings")
+				for _, warning := range warnings {
+					fmt.Println(warning)
+				}
+			}
+
+			sidecars, err := project.SidecarsByPrecedence()
+			if err != nil {
+				return fmt.Errorf("could not read sidecars: %w", err)
+			}
+			if len(sidecars) > 0 {
+				fmt.Println("\n## Sidecars (highest precedence first)")
+				for _, sidecar := range sidecars {
+					if sidecar.File == "" {
+						fmt.Println("tags of the audio files")
+						continue
+					}
+					fmt.Printf("%s (%s)\n", sidecar.File, sidecar.Source)
+				}
 			}
 
 			if project.Config.HasChapters {
 				fmt.Println("\n## Chapters")
-				chaptersContent, err := project.Chapters()
-				if err != nil {
-					return fmt.Errorf("could not get chapters: %w", err)
+				if err = printChapters(project); err != nil {
+					return err
 				}
-				fmt.Println(chaptersContent)
 			}
 
 			fmt.Println("\n## Metadata")
@@ -99,13 +163,16 @@ var checkCmd = &cobra.Command{
 var chaptersCmd = &cobra.Command{
 	Use:   "chapters <dir>",
 	Short: "Show chapters with applied rules",
+	Long: `Show chapters with applied rules
+
+Chapters changed by chapters.overrides are marked with what was changed.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		path, err := utils.GetValidFullpathFromArgs(args, 0)
 		if err != nil {
 			return fmt.Errorf("could not resolve path %s: %w", args[0], err)
 		}
 
-		projects, err := m4b.NewProjectsFromPath(path)
+		projects, err := loadProjects(cmd, path)
 		if err != nil {
 			return err
 		}
@@ -113,30 +180,45 @@ var chaptersCmd = &cobra.Command{
 			return fmt.Errorf("got no projects for path")
 		}
 
-		chaptersContent, err := projects[0].Chapters()
-		if err != nil {
-			return fmt.Errorf("could not get chapters: %w", err)
-		}
+		return printChapters(projects[0])
+	},
+}
 
-		fmt.Println(chaptersContent)
+// printChapters prints the chapter markers of the project, marking the
+// chapters changed by overrides.
+func printChapters(project *m4b.Project) error {
+	chapters, err := project.ChapterList()
+	if err != nil {
+		return fmt.Errorf("could not get chapters: %w", err)
+	}
+
+	markers := make([]string, 0, len(chapters))
+	for i, chapter := range chapters {
+		marker := chapter.ChapterMarker(i)
+		if overrides := chapter.Overrides(); len(overrides) > 0 {
+			marker += fmt.Sprintf("\t(override: %s)", strings.Join(overrides, ", "))
+		}
+		markers = append(markers, marker)
+	}
 
-		return nil
-	},
+	fmt.Println(strings.Join(markers, "\n\n"))
+	return nil
 }
 
 var metadataCmd = &cobra.Command{
 	Use:   "metadata <dir>",
 	Short: "Show metadata with applied rules",
 	Long: `Show metadata with applied rules
-	
-	Uses the first file for metadata.`,
+
+The metadata of all tracks is combined according to aggregation. By default
+the tags of the first track are used.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		path, err := utils.GetValidFullpathFromArgs(args, 0)
 		if err != nil {
 			return fmt.Errorf("could not resolve path %s: %w", args[0], err)
 		}
 
-		projects, err := m4b.NewProjectsFromPath(path)
+		projects, err := loadProjects(cmd, path)
 		if err != nil {
 			return fmt.Errorf("could not create project: %w", err)
 		}
@@ -161,7 +243,7 @@ var filenameCmd = &cobra.Command{
 			return fmt.Errorf("could not resolve path %s: %w", args[0], err)
 		}
 
-		projects, err := m4b.NewProjectsFromPath(path)
+		projects, err := loadProjects(cmd, path)
 		if err != nil {
 			return fmt.Errorf("could not load config %s: %w", path, err)
 		}
@@ -183,13 +265,17 @@ var filenameCmd = &cobra.Command{
 var filesCmd = &cobra.Command{
 	Use:   "files <dir>",
 	Short: "Show input files in processing order",
+	Long: `Show input files in processing order
+
+Each file is followed by the key that decided its position, e.g. the disc and
+track number or the playlist entry.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		path, err := utils.GetValidFullpathFromArgs(args, 0)
 		if err != nil {
 			return fmt.Errorf("could not resolve path %s: %w", args[0], err)
 		}
 
-		projects, err := m4b.NewProjectsFromPath(path)
+		projects, err := loadProjects(cmd, path)
 		if err != nil {
 			return fmt.Errorf("could not load config %s: %w", path, err)
 		}
@@ -203,7 +289,11 @@ var filesCmd = &cobra.Command{
 		}
 
 		for _, track := range tracks {
-			fmt.Println(track.File)
+			fmt.Printf("%s\t(%s)\n", track.File, track.OrderKey())
+		}
+
+		if err = projects[0].ValidateOrder(); err != nil {
+			fmt.Println("\nWarning:", err)
 		}
 
 		return nil
@@ -212,9 +302,13 @@ var filesCmd = &cobra.Command{
 
 func init() {
 	M4bCmd.AddCommand(generateCmd)
+	generateCmd.Flags().BoolP("interactive", "i", false, "Ask step by step for the settings and show a live preview")
 	M4bCmd.AddCommand(checkCmd)
 	checkCmd.AddCommand(chaptersCmd)
 	checkCmd.AddCommand(metadataCmd)
 	checkCmd.AddCommand(filenameCmd)
 	checkCmd.AddCommand(filesCmd)
+	for _, cmd := range []*cobra.Command{checkCmd, chaptersCmd, metadataCmd, filenameCmd, filesCmd} {
+		addSaveFlag(cmd)
+	}
 }
@@ -0,0 +1,126 @@
+package m4b
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/achwo/narr/utils"
+	"github.com/spf13/cobra"
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Debug metadata and chapter rules",
+}
+
+var rulesTraceCmd = &cobra.Command{
+	Use:   "trace <dir>",
+	Short: "Show what every rule does to every track",
+	Long: `Show what every rule does to every track
+
+For each track, all metadata rules and chapter rules are listed in order with
+the value before and after the rule, whether the rule matched and the capture
+groups of its regex.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := utils.GetValidFullpathFromArgs(args, 0)
+		if err != nil {
+			return fmt.Errorf("could not resolve path %s: %w", args[0], err)
+		}
+
+		projects, err := loadProjects(cmd, path)
+		if err != nil {
+			return fmt.Errorf("could not create project(s): %w", err)
+		}
+
+		for _, project := range projects {
+			fmt.Printf("# Project %s\n", project.Config.ProjectPath)
+
+			traces, err := project.RuleTrace()
+			if err != nil {
+				return fmt.Errorf("could not trace rules: %w", err)
+			}
+
+			for _, trace := range traces {
+				fmt.Printf("\n## %s\n", trace.Track.RelativePath())
+
+				if len(trace.Metadata) > 0 {
+					fmt.Println("### Metadata rules")
+					printTrace(trace.Metadata)
+				}
+
+				if len(trace.Chapter) > 0 {
+					fmt.Println("### Chapter rules")
+					printTrace(trace.Chapter)
+				}
+			}
+			fmt.Println()
+		}
+
+		return nil
+	},
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:     "test",
+	Short:   "Try a single regex rule on a value",
+	Example: `narr m4b rules test --regex "Folge (\d+): (.*)" --format "%s. %s" --value "Folge 5: Der Fluch"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		regex, _ := cmd.Flags().GetString("regex")
+		format, _ := cmd.Flags().GetString("format")
+		value, _ := cmd.Flags().GetString("value")
+
+		entry, err := m4b.TraceChapterRule(m4b.ChapterRule{Regex: regex, Format: format}, value)
+		if err != nil {
+			return fmt.Errorf("could not apply rule: %w", err)
+		}
+
+		printTrace([]m4b.RuleTraceEntry{entry})
+		return nil
+	},
+}
+
+func printTrace(entries []m4b.RuleTraceEntry) {
+	for _, entry := range entries {
+		fmt.Printf("[%d] %s\n", entry.Index, entry.Rule)
+
+		label := "value"
+		if entry.Tag != "" {
+			label = entry.Tag
+		}
+
+		switch {
+		case entry.Skipped:
+			fmt.Println("    skipped, condition does not hold")
+		case !entry.Matched:
+			fmt.Println("    no match")
+		case len(entry.Groups) > 0:
+			quoted := make([]string, 0, len(entry.Groups))
+			for _, group := range entry.Groups {
+				quoted = append(quoted, fmt.Sprintf("%q", group))
+			}
+			fmt.Printf("    matched, groups: %s\n", strings.Join(quoted, ", "))
+		default:
+			fmt.Println("    matched")
+		}
+
+		if entry.Before == entry.After {
+			fmt.Printf("    %s: %q (unchanged)\n", label, entry.Before)
+		} else {
+			fmt.Printf("    %s: %q -> %q\n", label, entry.Before, entry.After)
+		}
+	}
+}
+
+func init() {
+	M4bCmd.AddCommand(rulesCmd)
+	rulesCmd.AddCommand(rulesTraceCmd)
+	rulesCmd.AddCommand(rulesTestCmd)
+
+	rulesTestCmd.Flags().String("regex", "", "Regular expression with capture groups")
+	rulesTestCmd.MarkFlagRequired("regex")
+	rulesTestCmd.Flags().String("format", "", "Format with placeholders for the capture groups")
+	rulesTestCmd.MarkFlagRequired("format")
+	rulesTestCmd.Flags().String("value", "", "Value to apply the rule to")
+	rulesTestCmd.MarkFlagRequired("value")
+}
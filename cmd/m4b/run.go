@@ -3,7 +3,6 @@ package m4b
 import (
 	"fmt"
 
-	"github.com/achwo/narr/m4b"
 	"github.com/achwo/narr/utils"
 	"github.com/spf13/cobra"
 )
@@ -12,14 +11,12 @@ var runCmd = &cobra.Command{
 	Use:   "run",
 	Short: "Convert to m4b",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		recursive, _ := cmd.Flags().GetBool("recursive")
-
 		path, err := utils.GetValidFullpathFromArgs(args, 0)
 		if err != nil {
 			return fmt.Errorf("could not resolve path %s: %w", args[0], err)
 		}
 
-		projects, err := m4b.NewProjectsByArgs(path, recursive)
+		projects, err := loadProjects(cmd, path)
 
 		if err != nil {
 			return fmt.Errorf("could not create project(s): %w", err)
@@ -46,4 +43,5 @@ var runCmd = &cobra.Command{
 
 func init() {
 	M4bCmd.AddCommand(runCmd)
+	addSaveFlag(runCmd)
 }
@@ -0,0 +1,401 @@
+package m4b
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/achwo/narr/m4b"
+)
+
+var imageExtensions = []string{".jpg", ".jpeg", ".png"}
+
+// wizard asks step by step for the settings of a new config and renders a
+// preview of the resulting project after every answer.
+type wizard struct {
+	in      *bufio.Reader
+	out     io.Writer
+	dir     string
+	config  m4b.ProjectConfig
+	project *m4b.Project
+}
+
+func newWizard(
+	in io.Reader,
+	out io.Writer,
+	dir string,
+	suggestion *m4b.ConfigSuggestion,
+	deps m4b.ProjectDependencies,
+) (*wizard, error) {
+	config := suggestion.Config
+	config.ProjectPath = suggestion.TrackDir
+
+	project, err := m4b.NewProjectWithDeps(config, deps)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wizard{
+		in:      bufio.NewReader(in),
+		out:     out,
+		dir:     dir,
+		config:  config,
+		project: project,
+	}, nil
+}
+
+// Run executes all steps and returns the resulting config.
+func (w *wizard) Run() (m4b.ProjectConfig, error) {
+	steps := []func() error{
+		w.showTracks,
+		w.preview,
+		w.askNaming,
+		w.askCover,
+		w.askChapters,
+		w.askChapterRules,
+		w.askMetadataRules,
+	}
+
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return m4b.ProjectConfig{}, err
+		}
+	}
+
+	config := w.config
+	config.ProjectPath = ""
+	return config, nil
+}
+
+func (w *wizard) showTracks() error {
+	tracks, err := w.project.Tracks()
+	if err != nil {
+		return fmt.Errorf("could not get tracks: %w", err)
+	}
+
+	fmt.Fprintf(w.out, "# Detected %d tracks\n", len(tracks))
+	for _, track := range tracks {
+		title, duration, err := track.TitleAndDuration()
+		if err != nil {
+			return fmt.Errorf("could not read title of %s: %w", track.File, err)
+		}
+		rel, err := filepath.Rel(w.dir, track.File)
+		if err != nil {
+			rel = track.File
+		}
+		fmt.Fprintf(w.out, "%s\t%s\t%s\n", rel, formatDuration(duration), title)
+	}
+
+	return nil
+}
+
+func (w *wizard) preview() error {
+	preview, err := renderPreview(w.project, w.config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(w.out, preview)
+	return nil
+}
+
+// update applies the changed config and renders the preview again. If the
+// preview cannot be rendered with the changed config, the previous config is kept.
+func (w *wizard) update(config m4b.ProjectConfig) error {
+	project, err := w.project.WithConfig(config)
+	if err != nil {
+		fmt.Fprintln(w.out, "Change not applied:", err)
+		return nil
+	}
+
+	preview, err := renderPreview(project, config)
+	if err != nil {
+		fmt.Fprintln(w.out, "Change not applied:", err)
+		return nil
+	}
+
+	w.config = config
+	w.project = project
+	fmt.Fprint(w.out, preview)
+	return nil
+}
+
+func renderPreview(project *m4b.Project, config m4b.ProjectConfig) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("\n# Preview\n")
+
+	metadata, err := project.Metadata()
+	if err != nil {
+		return "", fmt.Errorf("could not get metadata: %w", err)
+	}
+	fmt.Fprintf(&sb, "## Metadata\n%s\n", metadata)
+
+	if config.HasChapters {
+		chapters, err := project.Chapters()
+		if err != nil {
+			return "", fmt.Errorf("could not get chapters: %w", err)
+		}
+		fmt.Fprintf(&sb, "\n## Chapters\n%s\n", chapters)
+	}
+
+	sb.WriteString("\n## Cover\n")
+	if config.CoverPath == "" {
+		sb.WriteString("embedded cover of the first track\n")
+	} else {
+		sb.WriteString(config.CoverPath + "\n")
+	}
+
+	filename, err := project.Filename()
+	if err != nil {
+		return "", fmt.Errorf("could not get filename: %w", err)
+	}
+	fmt.Fprintf(&sb, "\n## Filename\n%s\n\n", filename)
+
+	return sb.String(), nil
+}
+
+func (w *wizard) askNaming() error {
+	// the rules apply to every book, so the names of the first book would
+	// end up on all of them
+	if w.config.Multi {
+		fmt.Fprintln(w.out, "Artist and book title are read from each book in multi mode")
+		return nil
+	}
+
+	artist, album, err := w.project.ArtistAndBookTitle()
+	if err != nil {
+		return fmt.Errorf("could not read artist and book title: %w", err)
+	}
+
+	newArtist, err := w.ask("Artist", artist)
+	if err != nil {
+		return err
+	}
+	newAlbum, err := w.ask("Book title", album)
+	if err != nil {
+		return err
+	}
+
+	if newArtist == artist && newAlbum == album {
+		return nil
+	}
+
+	config := w.config
+	config.MetadataRules = slices.Clone(config.MetadataRules)
+	if newArtist != artist {
+		config.MetadataRules = append(config.MetadataRules, m4b.MetadataRule{Type: "set", Tag: "artist", Value: newArtist})
+	}
+	if newAlbum != album {
+		config.MetadataRules = append(config.MetadataRules, m4b.MetadataRule{Type: "set", Tag: "album", Value: newAlbum})
+	}
+
+	return w.update(config)
+}
+
+func (w *wizard) askCover() error {
+	options := []string{""}
+
+	entries, err := os.ReadDir(w.config.ProjectPath)
+	if err != nil {
+		return fmt.Errorf("could not read directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && slices.Contains(imageExtensions, strings.ToLower(filepath.Ext(entry.Name()))) {
+			options = append(options, entry.Name())
+		}
+	}
+
+	fmt.Fprintln(w.out, "Cover:")
+	current := 0
+	for i, option := range options {
+		label := option
+		if option == "" {
+			label = "embedded cover of the first track"
+		}
+		if option == w.config.CoverPath {
+			current = i
+		}
+		fmt.Fprintf(w.out, "  [%d] %s\n", i, label)
+	}
+	fmt.Fprintln(w.out, "  or enter a path")
+
+	answer, err := w.ask("Cover", strconv.Itoa(current))
+	if err != nil {
+		return err
+	}
+
+	cover := answer
+	if i, err := strconv.Atoi(answer); err == nil && i >= 0 && i < len(options) {
+		cover = options[i]
+	}
+
+	if cover == w.config.CoverPath {
+		return nil
+	}
+
+	config := w.config
+	config.CoverPath = cover
+	return w.update(config)
+}
+
+func (w *wizard) askChapters() error {
+	hasChapters, err := w.confirm("Generate chapters", w.config.HasChapters)
+	if err != nil {
+		return err
+	}
+
+	if hasChapters == w.config.HasChapters {
+		return nil
+	}
+
+	config := w.config
+	config.HasChapters = hasChapters
+	return w.update(config)
+}
+
+func (w *wizard) askChapterRules() error {
+	if !w.config.HasChapters {
+		return nil
+	}
+
+	for {
+		regex, err := w.ask("Add chapter rule regex (empty to continue)", "")
+		if err != nil || regex == "" {
+			return err
+		}
+		format, err := w.ask("Format", "%s")
+		if err != nil {
+			return err
+		}
+
+		config := w.config
+		config.ChapterRules = append(slices.Clone(config.ChapterRules), m4b.ChapterRule{Regex: regex, Format: format})
+		if err = w.update(config); err != nil {
+			return err
+		}
+	}
+}
+
+// fieldDefaults are the answers suggested for the fields of metadata rules.
+var fieldDefaults = map[string]string{"format": "%s"}
+
+func (w *wizard) askMetadataRules() error {
+	for {
+		tag, err := w.ask("Add metadata rule for tag (empty to finish)", "")
+		if err != nil || tag == "" {
+			return err
+		}
+
+		rule := m4b.MetadataRule{Tag: tag}
+		rule.Type, err = w.ask(fmt.Sprintf("Type (%s)", strings.Join(m4b.MetadataRuleTypes, ", ")), "regex")
+		if err != nil {
+			return err
+		}
+
+		for _, field := range m4b.MetadataRuleFields(rule.Type) {
+			value, err := w.ask(strings.ToUpper(field[:1])+field[1:], fieldDefaults[field])
+			if err != nil {
+				return err
+			}
+			if err = setRuleField(&rule, field, value); err != nil {
+				return err
+			}
+		}
+
+		if err = rule.Validate(); err != nil {
+			fmt.Fprintln(w.out, "Rule not added:", err)
+			continue
+		}
+
+		config := w.config
+		config.MetadataRules = append(slices.Clone(config.MetadataRules), rule)
+		if err = w.update(config); err != nil {
+			return err
+		}
+	}
+}
+
+// setRuleField sets the field of the rule named like in narr.yaml.
+func setRuleField(rule *m4b.MetadataRule, field string, value string) error {
+	switch field {
+	case "from":
+		rule.From = value
+	case "value":
+		rule.Value = value
+	case "regex":
+		rule.Regex = value
+	case "format":
+		rule.Format = value
+	case "replacement":
+		rule.Replacement = value
+	case "template":
+		rule.Template = value
+	case "case":
+		rule.Case = value
+	case "width":
+		if value == "" {
+			return nil
+		}
+		width, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("width '%s' is not a number", value)
+		}
+		rule.Width = width
+	default:
+		return fmt.Errorf("unknown rule field %s", field)
+	}
+	return nil
+}
+
+// ask prints the prompt and returns the answer, or def if the answer is empty.
+func (w *wizard) ask(prompt string, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", prompt, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", prompt)
+	}
+
+	// a closed input accepts the defaults of all remaining questions
+	line, err := w.in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("could not read answer: %w", err)
+	}
+
+	answer := strings.TrimSpace(line)
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+func (w *wizard) confirm(prompt string, def bool) (bool, error) {
+	options := "y/N"
+	if def {
+		options = "Y/n"
+	}
+
+	answer, err := w.ask(prompt+" ("+options+")", "")
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(answer) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func formatDuration(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second).String()
+}
@@ -0,0 +1,204 @@
+package m4b
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAudioFileProvider struct {
+	files []string
+	dirs  map[string][]string // files per directory, instead of files
+}
+
+func (f *fakeAudioFileProvider) AudioFiles(dir string) ([]string, error) {
+	if f.dirs != nil {
+		return f.dirs[dir], nil
+	}
+	return f.files, nil
+}
+
+func newTestWizard(t *testing.T, input string) (*wizard, *strings.Builder) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cover.jpg"), nil, 0644))
+
+	files := []string{filepath.Join(dir, "01.mp3"), filepath.Join(dir, "02.mp3")}
+	data := map[string]m4b.FileData{
+		files[0]: {Title: "Kapitel 1", Duration: 60, Metadata: ";FFMETADATA1\nartist=Autor\nalbum=Buch\ntitle=Kapitel 1\ntrack=1"},
+		files[1]: {Title: "Kapitel 2", Duration: 90, Metadata: ";FFMETADATA1\nartist=Autor\nalbum=Buch\ntitle=Kapitel 2\ntrack=2"},
+	}
+
+	processor := &m4b.NullAudioProcessor{Data: data}
+	deps := m4b.ProjectDependencies{
+		AudioFileProvider: &fakeAudioFileProvider{files: files},
+		AudioProcessor:    processor,
+		TrackFactory:      &m4b.FFmpegTrackFactory{AudioProcessor: processor},
+	}
+
+	out := &strings.Builder{}
+	suggestion := &m4b.ConfigSuggestion{Config: m4b.ProjectConfig{HasChapters: true}, TrackDir: dir}
+
+	w, err := newWizard(strings.NewReader(input), out, dir, suggestion, deps)
+	require.NoError(t, err)
+	return w, out
+}
+
+func TestWizard_Run(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  []string
+		config m4b.ProjectConfig
+		output string
+	}{
+		{
+			name:   "defaults on closed input",
+			config: m4b.ProjectConfig{HasChapters: true},
+		},
+		{
+			name:  "naming",
+			input: []string{"Anderer Autor", ""},
+			config: m4b.ProjectConfig{
+				HasChapters:   true,
+				MetadataRules: []m4b.MetadataRule{{Type: "set", Tag: "artist", Value: "Anderer Autor"}},
+			},
+			output: "/Anderer Autor/Buch/Buch.m4b",
+		},
+		{
+			name:   "cover from directory",
+			input:  []string{"", "", "1"},
+			config: m4b.ProjectConfig{HasChapters: true, CoverPath: "cover.jpg"},
+		},
+		{
+			name:   "no chapters",
+			input:  []string{"", "", "", "n"},
+			config: m4b.ProjectConfig{},
+		},
+		{
+			name:  "chapter rule",
+			input: []string{"", "", "", "", `Kapitel (\d+)`, "Chapter %s", ""},
+			config: m4b.ProjectConfig{
+				HasChapters:  true,
+				ChapterRules: []m4b.ChapterRule{{Regex: `Kapitel (\d+)`, Format: "Chapter %s"}},
+			},
+			output: "Chapter 1",
+		},
+		{
+			name:  "regex rule",
+			input: []string{"", "", "", "", "", "album", "", `(\w+)`, "Das %s"},
+			config: m4b.ProjectConfig{
+				HasChapters:   true,
+				MetadataRules: []m4b.MetadataRule{{Type: "regex", Tag: "album", Regex: `(\w+)`, Format: "Das %s"}},
+			},
+			output: "/Autor/Das Buch/Das Buch.m4b",
+		},
+		{
+			name:  "pad rule",
+			input: []string{"", "", "", "", "", "track", "pad", "3"},
+			config: m4b.ProjectConfig{
+				HasChapters:   true,
+				MetadataRules: []m4b.MetadataRule{{Type: "pad", Tag: "track", Width: 3}},
+			},
+		},
+		{
+			name:  "template rule",
+			input: []string{"", "", "", "", "", "comment", "template", "{{.artist}} liest {{.album}}"},
+			config: m4b.ProjectConfig{
+				HasChapters:   true,
+				MetadataRules: []m4b.MetadataRule{{Type: "template", Tag: "comment", Template: "{{.artist}} liest {{.album}}"}},
+			},
+			output: "comment=Autor liest Buch",
+		},
+		{
+			name:  "copy and case rules",
+			input: []string{"", "", "", "", "", "composer", "copy", "artist", "album", "case", "upper"},
+			config: m4b.ProjectConfig{
+				HasChapters: true,
+				MetadataRules: []m4b.MetadataRule{
+					{Type: "copy", Tag: "composer", From: "artist"},
+					{Type: "case", Tag: "album", Case: "upper"},
+				},
+			},
+			output: "/Autor/BUCH/BUCH.m4b",
+		},
+		{
+			name:   "invalid rule is not added",
+			input:  []string{"", "", "", "", "", "album", "case", "sideways"},
+			config: m4b.ProjectConfig{HasChapters: true},
+			output: "Rule not added: case rule requires case to be title, upper or lower, got 'sideways'",
+		},
+		{
+			name:   "unknown rule type is not added",
+			input:  []string{"", "", "", "", "", "album", "shout"},
+			config: m4b.ProjectConfig{HasChapters: true},
+			output: "Rule not added: unknown rule type: shout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := strings.Join(tt.input, "\n")
+			if input != "" {
+				input += "\n"
+			}
+			w, out := newTestWizard(t, input)
+
+			config, err := w.Run()
+			require.NoError(t, err)
+			require.Equal(t, tt.config, config)
+			require.Contains(t, out.String(), tt.output)
+		})
+	}
+}
+
+func TestWizard_RuleTypePromptListsAllTypes(t *testing.T) {
+	w, out := newTestWizard(t, strings.Repeat("\n", 5)+"album\n")
+
+	_, err := w.Run()
+	require.NoError(t, err)
+	for _, ruleType := range m4b.MetadataRuleTypes {
+		require.Contains(t, out.String(), ruleType)
+	}
+	require.Contains(t, out.String(), "Type (set, default, delete, regex, replace, copy, move, template, case, trim, pad) [regex]")
+}
+
+func TestWizard_MultiBookFolderKeepsNames(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "Erstes Buch")
+	second := filepath.Join(dir, "Zweites Buch")
+	require.NoError(t, os.Mkdir(first, 0755))
+	require.NoError(t, os.Mkdir(second, 0755))
+
+	files := map[string][]string{
+		first:  {filepath.Join(first, "01.mp3")},
+		second: {filepath.Join(second, "01.mp3")},
+	}
+	data := map[string]m4b.FileData{
+		files[first][0]:  {Title: "Kapitel 1", Duration: 60, Metadata: ";FFMETADATA1\nartist=Autor\nalbum=Erstes Buch"},
+		files[second][0]: {Title: "Kapitel 1", Duration: 60, Metadata: ";FFMETADATA1\nartist=Autor\nalbum=Zweites Buch"},
+	}
+
+	processor := &m4b.NullAudioProcessor{Data: data}
+	deps := m4b.ProjectDependencies{
+		AudioFileProvider: &fakeAudioFileProvider{dirs: files},
+		AudioProcessor:    processor,
+		TrackFactory:      &m4b.FFmpegTrackFactory{AudioProcessor: processor},
+	}
+
+	suggestion, err := m4b.SuggestConfig(dir, deps)
+	require.NoError(t, err)
+	require.True(t, suggestion.Config.Multi)
+
+	out := &strings.Builder{}
+	w, err := newWizard(strings.NewReader(""), out, dir, suggestion, deps)
+	require.NoError(t, err)
+
+	config, err := w.Run()
+	require.NoError(t, err)
+	require.Empty(t, config.MetadataRules)
+	require.Contains(t, out.String(), "Artist and book title are read from each book in multi mode")
+	require.NotContains(t, out.String(), "Book title")
+}
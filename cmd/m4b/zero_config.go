@@ -0,0 +1,67 @@
+package m4b
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/spf13/cobra"
+)
+
+// loadProjects creates the projects for path. If path is a directory without
+// narr.yaml, the config is built in zero-config mode, printed to stderr (so it
+// does not mix with output like exported chapters) and, with --save, written
+// to the directory. Every command that takes a project directory uses it.
+func loadProjects(cmd *cobra.Command, path string) ([]*m4b.Project, error) {
+	recursive, _ := cmd.Flags().GetBool("recursive")
+	save, _ := cmd.Flags().GetBool("save")
+
+	configPath := filepath.Join(path, "narr.yaml")
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not access %s: %w", path, err)
+	}
+
+	if _, err = os.Stat(configPath); recursive || !info.IsDir() || !errors.Is(err, os.ErrNotExist) {
+		if save {
+			return nil, errors.New("--save is only available for directories without narr.yaml")
+		}
+		return m4b.NewProjectsByArgs(path, recursive)
+	}
+
+	defaults, err := m4b.ReadDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	deps := m4b.DefaultProjectDependencies()
+	deps.AudioFileProvider = defaults.Input.AudioFileProvider()
+
+	suggestion, err := m4b.ZeroConfig(path, defaults, deps)
+	if err != nil {
+		return nil, fmt.Errorf("could not build config: %w", err)
+	}
+
+	yamlBytes, err := suggestion.YAML()
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal config, %w", err)
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "# No narr.yaml found, using zero-config:\n%s\n", yamlBytes)
+
+	if save {
+		fmt.Fprintln(cmd.ErrOrStderr(), "Writing config to", configPath)
+		if err = os.WriteFile(configPath, yamlBytes, 0644); err != nil {
+			return nil, fmt.Errorf("could not write config: %w", err)
+		}
+	}
+
+	return m4b.NewProjectsFromConfig(suggestion.Config, path)
+}
+
+// addSaveFlag adds --save, used by loadProjects, to cmd.
+func addSaveFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("save", false, "Save the zero-config as narr.yaml when the directory has none")
+}
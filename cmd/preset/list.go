@@ -0,0 +1,43 @@
+package preset
+
+import (
+	"fmt"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List available presets",
+	Example: "narr preset list",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		presets, err := m4b.ListPresets()
+		if err != nil {
+			return fmt.Errorf("could not list presets: %w", err)
+		}
+
+		if len(presets) == 0 {
+			dir, err := m4b.PresetDir()
+			if err != nil {
+				return err
+			}
+			fmt.Println("No presets found in", dir)
+			return nil
+		}
+
+		for _, preset := range presets {
+			if preset.Description != "" {
+				fmt.Printf("%s\t%s\n", preset.Name, preset.Description)
+			} else {
+				fmt.Println(preset.Name)
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	PresetCmd.AddCommand(listCmd)
+}
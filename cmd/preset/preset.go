@@ -0,0 +1,14 @@
+package preset
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// PresetCmd allows inspecting the rule presets that can be referenced from narr.yaml
+var PresetCmd = &cobra.Command{
+	Use:   "preset",
+	Short: "Work with rule presets",
+	Long: `The preset command lists and shows reusable rule presets.
+Presets are stored in ~/.config/narr/presets/<name>.yaml and are referenced
+in narr.yaml via "presets: [name]".`,
+}
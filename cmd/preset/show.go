@@ -0,0 +1,42 @@
+package preset
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var showCmd = &cobra.Command{
+	Use:     "show <name|path>",
+	Short:   "Print the rules of a preset",
+	Example: "narr preset show drei-fragezeichen",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current path: %w", err)
+		}
+
+		preset, err := m4b.LoadPreset(args[0], wd)
+		if err != nil {
+			return fmt.Errorf("could not load preset: %w", err)
+		}
+
+		out, err := yaml.Marshal(preset)
+		if err != nil {
+			return fmt.Errorf("could not marshal preset: %w", err)
+		}
+
+		fmt.Println("#", preset.Path)
+		fmt.Print(string(out))
+
+		return nil
+	},
+}
+
+func init() {
+	PresetCmd.AddCommand(showCmd)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/achwo/narr/cmd/files"
 	"github.com/achwo/narr/cmd/m4b"
 	"github.com/achwo/narr/cmd/metadata"
+	"github.com/achwo/narr/cmd/preset"
 	"github.com/spf13/cobra"
 )
 
@@ -29,6 +30,7 @@ func init() {
 	rootCmd.AddCommand(metadata.MetadataCmd)
 	rootCmd.AddCommand(files.FilesCmd)
 	rootCmd.AddCommand(m4b.M4bCmd)
+	rootCmd.AddCommand(preset.PresetCmd)
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
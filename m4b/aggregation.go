@@ -0,0 +1,184 @@
+package m4b
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Aggregation strategies, used in ProjectConfig.Aggregation
+const (
+	AggregateFirst             = "first"
+	AggregateMostCommon        = "most-common"
+	AggregateUnion             = "union"
+	AggregateRequireConsistent = "require-consistent"
+)
+
+// aggregationDefaultKey sets the strategy for all tags without an explicit one.
+const aggregationDefaultKey = "*"
+
+var aggregationStrategies = []string{AggregateFirst, AggregateMostCommon, AggregateUnion, AggregateRequireConsistent}
+
+// conflictTags are the tags that are expected to be equal for all tracks of a book.
+var conflictTags = []string{"album", "artist"}
+
+// MetadataConflict describes a tag on which the tracks of a project disagree.
+type MetadataConflict struct {
+	Tag    string
+	Values []TagValueCount // distinct values, most common first
+}
+
+// TagValueCount is a tag value along with the number of tracks having it.
+type TagValueCount struct {
+	Value string
+	Count int
+}
+
+func (c MetadataConflict) String() string {
+	values := make([]string, 0, len(c.Values))
+	for _, value := range c.Values {
+		values = append(values, fmt.Sprintf("'%s' (%d tracks)", value.Value, value.Count))
+	}
+	return fmt.Sprintf("tracks disagree on %s: %s", c.Tag, strings.Join(values, ", "))
+}
+
+func validateAggregation(aggregation map[string]string) error {
+	for tag, strategy := range aggregation {
+		if !slices.Contains(aggregationStrategies, strategy) {
+			return fmt.Errorf("unknown aggregation strategy '%s' for tag %s", strategy, tag)
+		}
+	}
+	return nil
+}
+
+// aggregateMetadata combines the metadata of all tracks into the book
+// metadata. The strategy of each tag decides how the values of the tracks
+// are combined. The default, first, takes the value of the first track, so
+// tags with that strategy only come from the first track. Tags with other
+// strategies are collected from all tracks.
+func aggregateMetadata(tracks []Track, aggregation map[string]string) (map[string]string, []string, error) {
+	var tagOrder []string
+	var firstTags map[string]string
+	values := map[string][]string{}
+
+	for i, track := range tracks {
+		tags, trackTagOrder, err := track.Metadata()
+		if err != nil {
+			return nil, nil, err
+		}
+		if i == 0 {
+			firstTags = tags
+		}
+
+		for _, tag := range trackTagOrder {
+			if _, seen := values[tag]; !seen {
+				if i > 0 && aggregationStrategy(aggregation, tag) == AggregateFirst {
+					continue
+				}
+				tagOrder = append(tagOrder, tag)
+				values[tag] = []string{}
+			}
+			if value := tags[tag]; value != "" {
+				values[tag] = append(values[tag], value)
+			}
+		}
+	}
+
+	metadata := make(map[string]string, len(tagOrder))
+
+	for _, tag := range tagOrder {
+		strategy := aggregationStrategy(aggregation, tag)
+		if strategy == AggregateFirst {
+			metadata[tag] = firstTags[tag]
+			continue
+		}
+
+		tagValues := values[tag]
+		if len(tagValues) == 0 {
+			metadata[tag] = ""
+			continue
+		}
+
+		switch strategy {
+		case AggregateMostCommon:
+			metadata[tag] = countValues(tagValues)[0].Value
+		case AggregateUnion:
+			var distinct []string
+			for _, value := range tagValues {
+				if !slices.Contains(distinct, value) {
+					distinct = append(distinct, value)
+				}
+			}
+			metadata[tag] = strings.Join(distinct, "; ")
+		case AggregateRequireConsistent:
+			if counts := countValues(tagValues); len(counts) > 1 {
+				return nil, nil, MetadataConflict{Tag: tag, Values: counts}.asError()
+			}
+			metadata[tag] = tagValues[0]
+		}
+	}
+
+	return metadata, tagOrder, nil
+}
+
+// aggregationStrategy returns the strategy for tag, falling back to the
+// default key and then to first.
+func aggregationStrategy(aggregation map[string]string, tag string) string {
+	if strategy, exists := aggregation[tag]; exists {
+		return strategy
+	}
+	if strategy, exists := aggregation[aggregationDefaultKey]; exists {
+		return strategy
+	}
+	return AggregateFirst
+}
+
+// metadataConflicts returns a conflict for each of the conflictTags the
+// tracks disagree on.
+func metadataConflicts(tracks []Track) ([]MetadataConflict, error) {
+	var conflicts []MetadataConflict
+
+	for _, tag := range conflictTags {
+		var tagValues []string
+		for _, track := range tracks {
+			tags, _, err := track.Metadata()
+			if err != nil {
+				return nil, err
+			}
+			if value := tags[tag]; value != "" {
+				tagValues = append(tagValues, value)
+			}
+		}
+
+		if counts := countValues(tagValues); len(counts) > 1 {
+			conflicts = append(conflicts, MetadataConflict{Tag: tag, Values: counts})
+		}
+	}
+
+	return conflicts, nil
+}
+
+// countValues counts the distinct values, most common first. Values with the
+// same count keep the order of their first occurrence.
+func countValues(values []string) []TagValueCount {
+	var counts []TagValueCount
+
+	for _, value := range values {
+		i := slices.IndexFunc(counts, func(c TagValueCount) bool { return c.Value == value })
+		if i < 0 {
+			counts = append(counts, TagValueCount{Value: value, Count: 1})
+		} else {
+			counts[i].Count++
+		}
+	}
+
+	slices.SortStableFunc(counts, func(a, b TagValueCount) int {
+		return b.Count - a.Count
+	})
+
+	return counts
+}
+
+func (c MetadataConflict) asError() error {
+	return fmt.Errorf("%s (aggregation of %s is require-consistent)", c.String(), c.Tag)
+}
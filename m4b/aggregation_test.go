@@ -0,0 +1,103 @@
+package m4b_test
+
+import (
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+var aggregationData = map[string]m4b.FileData{
+	"1.flac": {Title: "1", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Hobbit\nartist=Tolkien\ngenre=Fantasy\ncomment="},
+	"2.flac": {Title: "2", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Der Hobbit\nartist=Tolkien\ngenre=Klassiker\ncomment=CD 2"},
+	"3.flac": {Title: "3", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Der Hobbit\nartist=Tolkien\ngenre=Fantasy\ndate=2012"},
+}
+
+func TestAggregation(t *testing.T) {
+	tests := []struct {
+		name        string
+		aggregation map[string]string
+		expected    string
+	}{
+		{
+			name: "first track by default",
+			expected: `;FFMETADATA1
+album=Hobbit
+artist=Tolkien
+genre=Fantasy
+comment=`,
+		},
+		{
+			name:        "most common",
+			aggregation: map[string]string{"album": "most-common"},
+			expected: `;FFMETADATA1
+album=Der Hobbit
+artist=Tolkien
+genre=Fantasy
+comment=`,
+		},
+		{
+			name:        "tags of later tracks with other strategies",
+			aggregation: map[string]string{"date": "most-common", "comment": "union"},
+			expected: `;FFMETADATA1
+album=Hobbit
+artist=Tolkien
+genre=Fantasy
+comment=CD 2
+date=2012`,
+		},
+		{
+			name:        "union with default key",
+			aggregation: map[string]string{"*": "union", "album": "first"},
+			expected: `;FFMETADATA1
+album=Hobbit
+artist=Tolkien
+genre=Fantasy\; Klassiker
+comment=CD 2
+date=2012`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := m4b.ProjectConfig{ProjectPath: "/test", Aggregation: tt.aggregation}
+			deps := fakeDeps(aggregationData, []string{"1.flac", "2.flac", "3.flac"})
+			project, err := m4b.NewProjectWithDeps(config, deps)
+			require.NoError(t, err)
+
+			metadata, err := project.Metadata()
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, metadata)
+		})
+	}
+}
+
+func TestAggregation_RequireConsistent(t *testing.T) {
+	config := m4b.ProjectConfig{
+		ProjectPath: "/test",
+		Aggregation: map[string]string{"artist": "require-consistent", "album": "require-consistent"},
+	}
+	deps := fakeDeps(aggregationData, []string{"1.flac", "2.flac", "3.flac"})
+	project, err := m4b.NewProjectWithDeps(config, deps)
+	require.NoError(t, err)
+
+	_, err = project.Metadata()
+	require.ErrorContains(t, err, "tracks disagree on album: 'Der Hobbit' (2 tracks), 'Hobbit' (1 tracks)")
+}
+
+func TestAggregation_InvalidStrategy(t *testing.T) {
+	config := m4b.ProjectConfig{Aggregation: map[string]string{"album": "last"}}
+	require.ErrorContains(t, config.Validate(), "unknown aggregation strategy 'last' for tag album")
+}
+
+func TestMetadataConflicts(t *testing.T) {
+	deps := fakeDeps(aggregationData, []string{"1.flac", "2.flac", "3.flac"})
+	project, err := m4b.NewProjectWithDeps(m4b.ProjectConfig{ProjectPath: "/test"}, deps)
+	require.NoError(t, err)
+
+	conflicts, err := project.MetadataConflicts()
+	require.NoError(t, err)
+	require.Equal(t, []m4b.MetadataConflict{
+		{Tag: "album", Values: []m4b.TagValueCount{{Value: "Der Hobbit", Count: 2}, {Value: "Hobbit", Count: 1}}},
+	}, conflicts)
+}
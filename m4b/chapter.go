@@ -16,7 +16,24 @@ type Chapter struct {
 	title           string
 	previousChapter *Chapter
 	files           []File
-	index           int
+	overrides       []string // what chapters.overrides changed, see Overrides
+	baseIndex       int      // index before overrides were applied, -1 if inserted
+}
+
+// Title returns the title of the chapter.
+func (c *Chapter) Title() string {
+	return c.title
+}
+
+// Offset returns the start of the chapter in seconds.
+func (c *Chapter) Offset() float64 {
+	return c.offset()
+}
+
+// Overrides describes what chapters.overrides changed about the chapter,
+// e.g. "renamed from 'Intro'". It is empty for chapters without overrides.
+func (c *Chapter) Overrides() []string {
+	return c.overrides
 }
 
 func (c *Chapter) addFile(file File) {
@@ -26,17 +43,7 @@ func (c *Chapter) addFile(file File) {
 // ChapterMarker returns a formatted string containing chapter timing and title information
 // in the format used by common audiobook chapter markers
 func (c *Chapter) ChapterMarker(chapterIndex int) string {
-	formatTime := func(s float64) string {
-		duration := time.Duration(s * float64(time.Second))
-
-		hours := int(duration.Hours())
-		minutes := int(duration.Minutes()) % 60
-		seconds := int(duration.Seconds()) % 60
-		milliseconds := int(duration.Milliseconds()) % 1000
-		return fmt.Sprintf("%02d:%02d:%02d.%03d", hours, minutes, seconds, milliseconds)
-	}
-
-	offsetFormatted := formatTime(c.offset())
+	offsetFormatted := formatTimestamp(c.offset())
 
 	return fmt.Sprintf(
 		"CHAPTER%d=%s\nCHAPTER%dNAME=%s",
@@ -47,6 +54,17 @@ func (c *Chapter) ChapterMarker(chapterIndex int) string {
 	)
 }
 
+// formatTimestamp formats seconds as hh:mm:ss.mmm.
+func formatTimestamp(s float64) string {
+	duration := time.Duration(s * float64(time.Second))
+
+	hours := int(duration.Hours())
+	minutes := int(duration.Minutes()) % 60
+	seconds := int(duration.Seconds()) % 60
+	milliseconds := int(duration.Milliseconds()) % 1000
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", hours, minutes, seconds, milliseconds)
+}
+
 func (c *Chapter) duration() float64 {
 	duration := 0.0
 
@@ -0,0 +1,313 @@
+package m4b
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const chapterTableHeader = `# Edit the chapters and save to write the changes to narr.yaml as overrides.
+# - change a title to rename the chapter
+# - delete a line to drop the chapter, the previous chapter continues instead
+# - add a line "+ <start> - <title>" to insert a chapter, e.g. mid-file
+# The index and start of existing chapters can not be changed, the duration is
+# ignored. Lines starting with # are ignored.
+#
+# index	start	duration	title
+`
+
+// insertedChapterIndex is the index of inserted chapters in a chapter table.
+const insertedChapterIndex = "+"
+
+var (
+	chapterRowRegex  = regexp.MustCompile(`^(\d+)\s+(\S+)\s+(\S+)\s+(.+)$`)
+	insertedRowRegex = regexp.MustCompile(`^\+\s+(\S+)\s+(\S+)\s+(.+)$`)
+)
+
+// ChapterTable renders the chapters as a table for editing, one line per
+// chapter with its index before overrides, start, duration and title.
+// Inserted chapters have the index "+". See OverridesFromChapterTable.
+func (p *Project) ChapterTable() (string, error) {
+	chapters, err := p.ChapterList()
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(chapterTableHeader)
+
+	for _, chapter := range chapters {
+		index := insertedChapterIndex
+		if chapter.baseIndex >= 0 {
+			index = strconv.Itoa(chapter.baseIndex)
+		}
+		fmt.Fprintf(&sb, "%s\t%s\t%s\t%s\n", index, formatTimestamp(chapter.offset()), formatTimestamp(chapter.duration()), chapter.title)
+	}
+
+	return sb.String(), nil
+}
+
+// OverridesFromChapterTable compares an edited ChapterTable with the
+// computed chapters and returns the overrides that turn the computed
+// chapters into the edited ones. The result replaces Config.Chapters.Overrides.
+//
+// Existing overrides the edit still agrees with are kept as they are, e.g.
+// a rename by title or a merge. Only the remaining differences are added as
+// overrides by index.
+func (p *Project) OverridesFromChapterTable(table string) ([]ChapterOverride, error) {
+	computed, err := p.computedChapters()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := applyChapterOverrides(computed, p.Config.Chapters.Overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	starts := map[int]string{}
+	for _, chapter := range current {
+		if chapter.baseIndex >= 0 {
+			starts[chapter.baseIndex] = formatTimestamp(chapter.offset())
+		}
+	}
+
+	titles := map[int]string{}
+	var inserts []ChapterOverride
+
+	scanner := bufio.NewScanner(strings.NewReader(table))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if match := insertedRowRegex.FindStringSubmatch(line); match != nil {
+			insert := ChapterOverride{Insert: &ChapterInsert{At: match[1], Title: strings.TrimSpace(match[3])}}
+			if err := insert.Validate(); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			inserts = append(inserts, insert)
+			continue
+		}
+
+		match := chapterRowRegex.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d: expected '<index> <start> <duration> <title>' or '+ <start> - <title>', got '%s'", lineNumber, line)
+		}
+
+		index, _ := strconv.Atoi(match[1])
+		if index >= len(computed) {
+			return nil, fmt.Errorf("line %d: there is no chapter %d, use + to insert a chapter", lineNumber, index)
+		}
+		if _, exists := titles[index]; exists {
+			return nil, fmt.Errorf("line %d: chapter %d is listed twice", lineNumber, index)
+		}
+		if start, ok := starts[index]; ok && start != match[2] {
+			return nil, fmt.Errorf("line %d: the start of chapter %d can not be changed, use + to insert a chapter", lineNumber, index)
+		}
+
+		titles[index] = strings.TrimSpace(match[4])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var overrides []ChapterOverride
+	for _, override := range p.Config.Chapters.Overrides {
+		if override.Insert != nil {
+			if i := slices.IndexFunc(inserts, override.Insert.sameAs); i >= 0 {
+				inserts = slices.Delete(inserts, i, i+1)
+				overrides = append(overrides, override)
+			}
+			continue
+		}
+		if override.keptBy(computed, titles) {
+			overrides = append(overrides, override)
+		}
+	}
+
+	kept, err := applyChapterOverrides(computed, overrides)
+	if err != nil {
+		return nil, err
+	}
+	keptTitles := map[int]string{}
+	for _, chapter := range kept {
+		if chapter.baseIndex >= 0 {
+			keptTitles[chapter.baseIndex] = chapter.title
+		}
+	}
+
+	for i := range computed {
+		title, wanted := titles[i]
+		keptTitle, present := keptTitles[i]
+		switch {
+		case !wanted && present:
+			overrides = append(overrides, ChapterOverride{Index: &i, Drop: true})
+		case wanted && present && title != keptTitle:
+			overrides = append(overrides, ChapterOverride{Index: &i, Rename: title})
+		}
+	}
+	overrides = append(overrides, inserts...)
+
+	if _, err := applyChapterOverrides(computed, overrides); err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+// keptBy reports whether the edited titles by index still agree with the
+// override, so it can stay as it is. Inserts are compared by sameAs instead.
+func (o *ChapterOverride) keptBy(computed []*Chapter, titles map[int]string) bool {
+	matched := false
+	for i, chapter := range computed {
+		if !o.matches(chapter, i) {
+			continue
+		}
+		matched = true
+
+		title, present := titles[i]
+		_, nextPresent := titles[i+1]
+		switch {
+		case o.Drop && present:
+			return false
+		case o.Merge && (!present || nextPresent):
+			return false
+		case o.Rename != "" && (!present || title != o.Rename):
+			return false
+		}
+	}
+	return matched
+}
+
+// sameAs reports whether the inserted chapter of other has the same title
+// and starts at the same millisecond.
+func (i *ChapterInsert) sameAs(other ChapterOverride) bool {
+	at, err := parseTimestamp(i.At)
+	if err != nil {
+		return false
+	}
+	otherAt, err := parseTimestamp(other.Insert.At)
+	if err != nil {
+		return false
+	}
+	return i.Title == other.Insert.Title && math.Round(at*1000) == math.Round(otherAt*1000)
+}
+
+// SaveChapterOverrides replaces chapters.overrides in the config file,
+// keeping the rest of the file including comments as is.
+func SaveChapterOverrides(configPath string, overrides []ChapterOverride) error {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("could not read config %s: %w", configPath, err)
+	}
+
+	var doc yaml.Node
+	if err = yaml.Unmarshal(content, &doc); err != nil {
+		return fmt.Errorf("could not parse config %s: %w", configPath, err)
+	}
+
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config %s is not a mapping", configPath)
+	}
+
+	chapters := mappingValue(root, "chapters")
+	if chapters == nil || chapters.Kind != yaml.MappingNode {
+		if len(overrides) == 0 {
+			return nil
+		}
+		chapters = &yaml.Node{Kind: yaml.MappingNode}
+		setMappingValue(root, "chapters", chapters)
+	}
+
+	if len(overrides) == 0 {
+		deleteMappingValue(chapters, "overrides")
+	} else {
+		var value yaml.Node
+		if err = value.Encode(overrides); err != nil {
+			return fmt.Errorf("could not encode overrides: %w", err)
+		}
+		setMappingValue(chapters, "overrides", &value)
+	}
+
+	if len(chapters.Content) == 0 {
+		deleteMappingValue(root, "chapters")
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(yamlIndent(content))
+	if err = encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("could not marshal config: %w", err)
+	}
+	if err = encoder.Close(); err != nil {
+		return fmt.Errorf("could not marshal config: %w", err)
+	}
+
+	if err = os.WriteFile(configPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("could not write config %s: %w", configPath, err)
+	}
+	return nil
+}
+
+// yamlIndent returns the indentation used in content, the smallest indent
+// of a line that is not a comment. Without indented lines it is 4, the
+// indentation narr.yaml is generated with.
+func yamlIndent(content []byte) int {
+	indent := 0
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		spaces := len(line) - len(trimmed)
+		if spaces == 0 || trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if indent == 0 || spaces < indent {
+			indent = spaces
+		}
+	}
+	if indent == 0 {
+		return 4
+	}
+	return indent
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+func deleteMappingValue(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
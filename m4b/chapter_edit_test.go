@@ -0,0 +1,149 @@
+package m4b_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+func chapterEditProject(t *testing.T, overrides []m4b.ChapterOverride) *m4b.Project {
+	t.Helper()
+
+	config := m4b.ProjectConfig{ProjectPath: "/book", Chapters: m4b.ChaptersConfig{Overrides: overrides}}
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(chapterData, chapterFiles))
+	require.NoError(t, err)
+	return project
+}
+
+func TestChapterTable(t *testing.T) {
+	project := chapterEditProject(t, []m4b.ChapterOverride{
+		{Index: chapterIndex(0), Drop: true},
+		{Insert: &m4b.ChapterInsert{At: "3:00", Title: "Mitte"}},
+	})
+
+	table, err := project.ChapterTable()
+	require.NoError(t, err)
+	requireGolden(t, "chapters/table", table)
+}
+
+func TestOverridesFromChapterTable(t *testing.T) {
+	project := chapterEditProject(t, nil)
+	table, err := project.ChapterTable()
+	require.NoError(t, err)
+
+	edited := strings.NewReplacer(
+		"0\t00:00:00.000\t00:01:00.000\tIntro\n", "0\t00:00:00.000\t00:01:00.000\tProlog\n",
+		"1\t00:01:00.000\t00:01:01.500\tIntermezzo\n", "",
+	).Replace(table) + "+   00:03:00.250   -   Mitten in Kapitel 1\n"
+
+	overrides, err := project.OverridesFromChapterTable(edited)
+	require.NoError(t, err)
+	require.Equal(t, []m4b.ChapterOverride{
+		{Index: chapterIndex(0), Rename: "Prolog"},
+		{Index: chapterIndex(1), Drop: true},
+		{Insert: &m4b.ChapterInsert{At: "00:03:00.250", Title: "Mitten in Kapitel 1"}},
+	}, overrides)
+
+	// editing the result again without changes yields the same overrides
+	project = chapterEditProject(t, overrides)
+	table, err = project.ChapterTable()
+	require.NoError(t, err)
+
+	again, err := project.OverridesFromChapterTable(table)
+	require.NoError(t, err)
+	require.Equal(t, overrides, again)
+}
+
+func TestOverridesFromChapterTable_KeepsUntouchedOverrides(t *testing.T) {
+	project := chapterEditProject(t, []m4b.ChapterOverride{
+		{Title: "Intermezzo", Rename: "Zwischenspiel"},
+		{Index: chapterIndex(1), Merge: true},
+		{Insert: &m4b.ChapterInsert{At: "3:00", Title: "Mitte"}},
+	})
+	table, err := project.ChapterTable()
+	require.NoError(t, err)
+
+	again, err := project.OverridesFromChapterTable(table)
+	require.NoError(t, err)
+	require.Equal(t, project.Config.Chapters.Overrides, again)
+
+	edited := strings.Replace(table, "\tIntro\n", "\tProlog\n", 1)
+	overrides, err := project.OverridesFromChapterTable(edited)
+	require.NoError(t, err)
+	require.Equal(t, append(project.Config.Chapters.Overrides,
+		m4b.ChapterOverride{Index: chapterIndex(0), Rename: "Prolog"},
+	), overrides)
+}
+
+func TestOverridesFromChapterTable_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+		err   string
+	}{
+		{"malformed", "0 Intro\n", "line 1: expected '<index> <start> <duration> <title>'"},
+		{"unknown index", "9\t00:00:00.000\t-\tx\n", "line 1: there is no chapter 9"},
+		{"listed twice", "0\t00:00:00.000\t-\tx\n0\t00:00:00.000\t-\ty\n", "line 2: chapter 0 is listed twice"},
+		{"start changed", "0\t00:00:01.000\t-\tx\n", "line 1: the start of chapter 0 can not be changed"},
+		{"invalid insert", "0\t00:00:00.000\t-\tIntro\n+ 1:xx - x\n", "line 2: insert at invalid"},
+		{"nothing left", "# all deleted\n", "overrides drop all chapters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := chapterEditProject(t, nil).OverridesFromChapterTable(tt.table)
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestSaveChapterOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		overrides []m4b.ChapterOverride
+		expected  string
+	}{
+		{
+			name:      "adds chapters",
+			config:    "# the cover\ncoverPath: cover.jpg\n",
+			overrides: []m4b.ChapterOverride{{Index: chapterIndex(2), Rename: "Prolog"}},
+			expected:  "# the cover\ncoverPath: cover.jpg\nchapters:\n    overrides:\n        - index: 2\n          rename: Prolog\n",
+		},
+		{
+			name:      "replaces overrides",
+			config:    "chapters:\n    grouping: per-disc # one per CD\n    overrides:\n        - index: 0\n          drop: true\n",
+			overrides: []m4b.ChapterOverride{{Insert: &m4b.ChapterInsert{At: "1:00", Title: "x"}}},
+			expected:  "chapters:\n    grouping: per-disc # one per CD\n    overrides:\n        - insert:\n            at: \"1:00\"\n            title: x\n",
+		},
+		{
+			name:      "keeps two space indentation",
+			config:    "chapters:\n  grouping: per-disc\n",
+			overrides: []m4b.ChapterOverride{{Index: chapterIndex(2), Rename: "Prolog"}},
+			expected:  "chapters:\n  grouping: per-disc\n  overrides:\n    - index: 2\n      rename: Prolog\n",
+		},
+		{
+			name:      "removes empty chapters",
+			config:    "hasChapters: true\nchapters:\n    overrides:\n        - index: 0\n          drop: true\n",
+			overrides: nil,
+			expected:  "hasChapters: true\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "narr.yaml")
+			require.NoError(t, os.WriteFile(configPath, []byte(tt.config), 0644))
+
+			require.NoError(t, m4b.SaveChapterOverrides(configPath, tt.overrides))
+
+			content, err := os.ReadFile(configPath)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(content))
+		})
+	}
+}
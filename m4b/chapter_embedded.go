@@ -0,0 +1,119 @@
+package m4b
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Handling of chapters embedded in the input files, used in ChaptersConfig.Embedded
+const (
+	EmbeddedIgnore  = "ignore"
+	EmbeddedFlatten = "flatten"
+	EmbeddedNested  = "nested"
+)
+
+var embeddedModes = []string{EmbeddedIgnore, EmbeddedFlatten, EmbeddedNested}
+
+// nestedTitleSeparator joins the title of a chapter and the titles of the
+// chapters embedded in its files in nested mode.
+const nestedTitleSeparator = " / "
+
+func validateEmbedded(embedded string) error {
+	if embedded != "" && !slices.Contains(embeddedModes, embedded) {
+		return fmt.Errorf("unknown embedded '%s', expected one of %v", embedded, embeddedModes)
+	}
+	return nil
+}
+
+// embedChapters replaces the files of the chapters that have chapters
+// embedded by one chapter per embedded chapter, shifted by the offset of the
+// file. Files without embedded chapters and segments of cue sheets are kept
+// as they are.
+func (p *Project) embedChapters(chapters []*Chapter, tracks []Track) ([]*Chapter, error) {
+	mode := p.Config.Chapters.Embedded
+	if mode == "" || mode == EmbeddedIgnore {
+		return chapters, nil
+	}
+
+	segments := map[string]bool{}
+	for _, track := range tracks {
+		if _, _, isSegment := track.Segment(); isSegment {
+			segments[track.File] = true
+		}
+	}
+
+	var result []*Chapter
+	add := func(title string, files ...File) *Chapter {
+		chapter := &Chapter{title: title, files: files}
+		if len(result) > 0 {
+			chapter.previousChapter = result[len(result)-1]
+		}
+		chapter.baseIndex = len(result)
+		result = append(result, chapter)
+		return chapter
+	}
+
+	for _, chapter := range chapters {
+		var current *Chapter
+
+		for _, file := range chapter.files {
+			var embedded []ChapterTiming
+			if !segments[file.Name] {
+				var err error
+				embedded, err = p.deps.AudioProcessor.ReadChapters(file.Name)
+				if err != nil {
+					return nil, fmt.Errorf("could not read embedded chapters of %s: %w", file.Name, err)
+				}
+			}
+			embedded = chaptersWithin(embedded, file.Duration)
+
+			if len(embedded) == 0 {
+				if current == nil {
+					current = add(chapter.title, file)
+				} else {
+					current.addFile(file)
+				}
+				continue
+			}
+
+			for i, inner := range embedded {
+				start := inner.Start
+				if i == 0 {
+					start = 0
+				}
+				end := file.Duration
+				if i+1 < len(embedded) {
+					end = embedded[i+1].Start
+				}
+				if end <= start {
+					continue // starts together with the next chapter
+				}
+
+				title := inner.Title
+				if mode == EmbeddedNested {
+					title = chapter.title + nestedTitleSeparator + inner.Title
+				}
+				current = add(title, File{Name: file.Name, Duration: end - start})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// chaptersWithin returns the chapters starting within duration, ordered by
+// their start.
+func chaptersWithin(chapters []ChapterTiming, duration float64) []ChapterTiming {
+	var within []ChapterTiming
+	for _, chapter := range chapters {
+		if chapter.Start < duration {
+			within = append(within, chapter)
+		}
+	}
+
+	slices.SortStableFunc(within, func(a, b ChapterTiming) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	return within
+}
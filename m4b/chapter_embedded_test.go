@@ -0,0 +1,87 @@
+package m4b_test
+
+import (
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+var partFiles = []string{"/book/Teil 1.m4b", "/book/Teil 2.m4b", "/book/Nachwort.m4a"}
+
+var partData = map[string]m4b.FileData{
+	"/book/Teil 1.m4b": {
+		Title:    "Teil 1",
+		Duration: 600,
+		Metadata: ";FFMETADATA1\ntrack=1",
+		Chapters: []m4b.ChapterTiming{
+			{Start: 0, End: 200, Title: "Kapitel 1"},
+			{Start: 200, End: 450.5, Title: "Kapitel 2"},
+			{Start: 450.5, End: 600, Title: "Kapitel 3"},
+		},
+	},
+	"/book/Teil 2.m4b": {
+		Title:    "Teil 2",
+		Duration: 300,
+		Metadata: ";FFMETADATA1\ntrack=2",
+		Chapters: []m4b.ChapterTiming{
+			// unordered and partly beyond the end of the file
+			{Start: 120, End: 300, Title: "Kapitel 5"},
+			{Start: 0.1, End: 120, Title: "Kapitel 4"},
+			{Start: 400, End: 500, Title: "Bonus"},
+		},
+	},
+	"/book/Nachwort.m4a": {Title: "Nachwort", Duration: 90, Metadata: ";FFMETADATA1\ntrack=3"},
+}
+
+func TestChapters_Embedded(t *testing.T) {
+	for _, embedded := range []string{"ignore", "flatten", "nested"} {
+		t.Run(embedded, func(t *testing.T) {
+			config := m4b.ProjectConfig{
+				ProjectPath: "/book",
+				Chapters:    m4b.ChaptersConfig{Embedded: embedded},
+			}
+			require.NoError(t, config.Validate())
+
+			project, err := m4b.NewProjectWithDeps(config, fakeDeps(partData, partFiles))
+			require.NoError(t, err)
+
+			chapters, err := project.Chapters()
+			require.NoError(t, err)
+			requireGolden(t, "chapters/embedded-"+embedded, chapters)
+		})
+	}
+}
+
+func TestChapters_EmbeddedOverrides(t *testing.T) {
+	config := m4b.ProjectConfig{
+		ProjectPath: "/book",
+		Chapters: m4b.ChaptersConfig{
+			Embedded:  "flatten",
+			Overrides: []m4b.ChapterOverride{{Index: chapterIndex(3), Rename: "Kapitel 4: Die Rückkehr"}},
+		},
+	}
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(partData, partFiles))
+	require.NoError(t, err)
+
+	chapters, err := project.ChapterTimings()
+	require.NoError(t, err)
+	require.Equal(t, m4b.ChapterTiming{Start: 600, End: 720, Title: "Kapitel 4: Die Rückkehr"}, chapters[3])
+}
+
+func TestChaptersConfig_ValidateEmbedded(t *testing.T) {
+	tests := []struct {
+		name     string
+		chapters m4b.ChaptersConfig
+		err      string
+	}{
+		{"unknown", m4b.ChaptersConfig{Embedded: "merge"}, "unknown embedded 'merge'"},
+		{"with source file", m4b.ChaptersConfig{Source: "file", File: "x", Embedded: "flatten"}, "embedded is not allowed with source file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.chapters.Validate(), tt.err)
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package m4b
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Formats chapters can be exported to, besides ChapterFormatFFMetadata,
+// ChapterFormatMP4Chaps and ChapterFormatPodcastJSON
+const (
+	ChapterFormatCue  = "cue"
+	ChapterFormatJSON = "json"
+	ChapterFormatVTT  = "vtt"
+	ChapterFormatSRT  = "srt"
+	ChapterFormatCSV  = "csv"
+)
+
+// ChapterExportFormats are the formats supported by ExportChapters.
+var ChapterExportFormats = []string{
+	ChapterFormatCue, ChapterFormatFFMetadata, ChapterFormatMP4Chaps, ChapterFormatJSON,
+	ChapterFormatPodcastJSON, ChapterFormatVTT, ChapterFormatSRT, ChapterFormatCSV,
+}
+
+// ChapterTiming is a chapter with its start and end in seconds.
+type ChapterTiming struct {
+	Start float64
+	End   float64
+	Title string
+}
+
+// ChapterTimings returns the chapters of the audiobook with their start and end.
+func (p *Project) ChapterTimings() ([]ChapterTiming, error) {
+	chapters, err := p.ChapterList()
+	if err != nil {
+		return nil, err
+	}
+
+	timings := make([]ChapterTiming, 0, len(chapters))
+	for _, chapter := range chapters {
+		start := chapter.offset()
+		timings = append(timings, ChapterTiming{Start: start, End: start + chapter.duration(), Title: chapter.title})
+	}
+	return timings, nil
+}
+
+// ExportChapters renders the chapters in the given format. audioFile is the
+// name of the audio file the chapters belong to, it is referenced by cue sheets.
+func ExportChapters(chapters []ChapterTiming, format string, audioFile string) (string, error) {
+	switch format {
+	case ChapterFormatCue:
+		return exportCue(chapters, audioFile), nil
+	case ChapterFormatFFMetadata:
+		return exportFFMetadata(chapters), nil
+	case ChapterFormatMP4Chaps:
+		return exportMP4Chaps(chapters), nil
+	case ChapterFormatJSON:
+		return exportJSON(chapters)
+	case ChapterFormatPodcastJSON:
+		return exportPodcastJSON(chapters)
+	case ChapterFormatVTT:
+		return exportSubtitles(chapters, "WEBVTT\n\n", "."), nil
+	case ChapterFormatSRT:
+		return exportSubtitles(chapters, "", ","), nil
+	case ChapterFormatCSV:
+		return exportCSV(chapters)
+	}
+	return "", fmt.Errorf("unknown chapter format '%s', expected one of %v", format, ChapterExportFormats)
+}
+
+func exportCue(chapters []ChapterTiming, audioFile string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "FILE \"%s\" WAVE\n", cueQuote(audioFile))
+
+	for i, chapter := range chapters {
+		// cue sheets count frames, 75 per second
+		frames := int(math.Round(chapter.Start * 75))
+		fmt.Fprintf(&sb, "  TRACK %02d AUDIO\n", i+1)
+		fmt.Fprintf(&sb, "    TITLE \"%s\"\n", cueQuote(chapter.Title))
+		fmt.Fprintf(&sb, "    INDEX 01 %02d:%02d:%02d\n", frames/75/60, frames/75%60, frames%75)
+	}
+
+	return sb.String()
+}
+
+// cueQuote replaces double quotes, which cue sheets can not escape.
+func cueQuote(value string) string {
+	return strings.ReplaceAll(value, `"`, "'")
+}
+
+func exportFFMetadata(chapters []ChapterTiming) string {
+	var sb strings.Builder
+	sb.WriteString(";FFMETADATA1\n")
+
+	for _, chapter := range chapters {
+		fmt.Fprintf(
+			&sb,
+			"\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			milliseconds(chapter.Start),
+			milliseconds(chapter.End),
+			escapeMetadataValue(chapter.Title),
+		)
+	}
+
+	return sb.String()
+}
+
+func exportMP4Chaps(chapters []ChapterTiming) string {
+	var sb strings.Builder
+	for _, chapter := range chapters {
+		fmt.Fprintf(&sb, "%s %s\n", formatTimestamp(chapter.Start), chapter.Title)
+	}
+	return sb.String()
+}
+
+func exportJSON(chapters []ChapterTiming) (string, error) {
+	type jsonChapter struct {
+		Index int     `json:"index"`
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+		Title string  `json:"title"`
+	}
+
+	list := make([]jsonChapter, 0, len(chapters))
+	for i, chapter := range chapters {
+		list = append(list, jsonChapter{Index: i, Start: seconds(chapter.Start), End: seconds(chapter.End), Title: chapter.Title})
+	}
+
+	return marshalJSON(list)
+}
+
+func exportPodcastJSON(chapters []ChapterTiming) (string, error) {
+	type podcastChapter struct {
+		StartTime float64 `json:"startTime"`
+		EndTime   float64 `json:"endTime"`
+		Title     string  `json:"title"`
+	}
+
+	file := struct {
+		Version  string           `json:"version"`
+		Chapters []podcastChapter `json:"chapters"`
+	}{Version: "1.2.0"}
+
+	for _, chapter := range chapters {
+		file.Chapters = append(file.Chapters, podcastChapter{
+			StartTime: seconds(chapter.Start),
+			EndTime:   seconds(chapter.End),
+			Title:     chapter.Title,
+		})
+	}
+
+	return marshalJSON(file)
+}
+
+func marshalJSON(value any) (string, error) {
+	bytes, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("could not marshal chapters: %w", err)
+	}
+	return string(bytes) + "\n", nil
+}
+
+// exportSubtitles renders the chapters as numbered cues, like SubRip and
+// WebVTT chapter tracks. They only differ by the header and the separator
+// of the milliseconds.
+func exportSubtitles(chapters []ChapterTiming, header string, separator string) string {
+	timestamp := func(s float64) string {
+		formatted := formatTimestamp(s)
+		return formatted[:len(formatted)-4] + separator + formatted[len(formatted)-3:]
+	}
+
+	var sb strings.Builder
+	sb.WriteString(header)
+
+	for i, chapter := range chapters {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1, timestamp(chapter.Start), timestamp(chapter.End), chapter.Title)
+	}
+
+	return sb.String()
+}
+
+func exportCSV(chapters []ChapterTiming) (string, error) {
+	var sb strings.Builder
+	writer := csv.NewWriter(&sb)
+
+	records := [][]string{{"index", "start", "end", "title"}}
+	for i, chapter := range chapters {
+		records = append(records, []string{
+			fmt.Sprint(i), formatTimestamp(chapter.Start), formatTimestamp(chapter.End), chapter.Title,
+		})
+	}
+
+	if err := writer.WriteAll(records); err != nil {
+		return "", fmt.Errorf("could not write csv: %w", err)
+	}
+	return sb.String(), nil
+}
+
+func milliseconds(s float64) int64 {
+	return int64(math.Round(s * 1000))
+}
+
+// seconds rounds to milliseconds, so floating point noise does not end up in
+// the exported files.
+func seconds(s float64) float64 {
+	return float64(milliseconds(s)) / 1000
+}
@@ -0,0 +1,54 @@
+package m4b_test
+
+import (
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportChapters(t *testing.T) {
+	config := m4b.ProjectConfig{
+		ProjectPath: "/book",
+		Chapters: m4b.ChaptersConfig{Overrides: []m4b.ChapterOverride{
+			{Index: chapterIndex(1), Rename: `Intermezzo; "Pause" = 1`},
+		}},
+	}
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(chapterData, chapterFiles))
+	require.NoError(t, err)
+
+	chapters, err := project.ChapterTimings()
+	require.NoError(t, err)
+	require.Equal(t, m4b.ChapterTiming{Start: 60, End: 121.5, Title: `Intermezzo; "Pause" = 1`}, chapters[1])
+
+	for _, format := range m4b.ChapterExportFormats {
+		t.Run(format, func(t *testing.T) {
+			exported, err := m4b.ExportChapters(chapters, format, "Das Buch.m4b")
+			require.NoError(t, err)
+			requireGolden(t, "chapters/export/book."+format, exported)
+		})
+	}
+}
+
+func TestExportChapters_RoundTrip(t *testing.T) {
+	chapters := []m4b.ChapterTiming{
+		{Start: 0, End: 60, Title: "Intro"},
+		{Start: 60, End: 121.5, Title: "Intermezzo = Pause"},
+	}
+
+	for _, format := range []string{"ffmetadata", "mp4chaps", "podcast-json"} {
+		t.Run(format, func(t *testing.T) {
+			exported, err := m4b.ExportChapters(chapters, format, "book.m4b")
+			require.NoError(t, err)
+
+			imported, err := m4b.ParseChapters([]byte(exported), "")
+			require.NoError(t, err)
+			require.Equal(t, []m4b.ImportedChapter{{Start: 0, Title: "Intro"}, {Start: 60, Title: "Intermezzo = Pause"}}, imported)
+		})
+	}
+}
+
+func TestExportChapters_UnknownFormat(t *testing.T) {
+	_, err := m4b.ExportChapters(nil, "ogm", "book.m4b")
+	require.ErrorContains(t, err, "unknown chapter format 'ogm'")
+}
@@ -0,0 +1,397 @@
+package m4b
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Formats of chapter files, used in ChaptersConfig.Format
+const (
+	ChapterFormatMP4Chaps    = "mp4chaps"
+	ChapterFormatFFMetadata  = "ffmetadata"
+	ChapterFormatPodcastJSON = "podcast-json"
+	ChapterFormatAudibleJSON = "audible-json"
+	ChapterFormatOGM         = "ogm"
+)
+
+var chapterImportFormats = []string{
+	ChapterFormatMP4Chaps, ChapterFormatFFMetadata, ChapterFormatPodcastJSON, ChapterFormatAudibleJSON, ChapterFormatOGM,
+}
+
+// ImportedChapter is a chapter read from a chapter file.
+type ImportedChapter struct {
+	Start float64 // in seconds
+	Title string
+}
+
+var (
+	ogmLineRegex      = regexp.MustCompile(`^CHAPTER(\d+)(NAME)?=(.*)$`)
+	mp4ChapsLineRegex = regexp.MustCompile(`^(\d+:\d{2}:\d{2}(?:\.\d+)?)\s+(.*)$`)
+)
+
+// ReadChapterFile reads the chapters of a chapter file in the given format.
+// With an empty format, the format is detected from the content.
+func ReadChapterFile(path string, format string) ([]ImportedChapter, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read chapter file %s: %w", path, err)
+	}
+
+	chapters, err := ParseChapters(content, format)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse chapter file %s: %w", path, err)
+	}
+	return chapters, nil
+}
+
+// ParseChapters parses chapters in the given format. With an empty format,
+// the format is detected from the content. The chapters are returned in the
+// order of the content.
+func ParseChapters(content []byte, format string) ([]ImportedChapter, error) {
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+
+	if format == "" {
+		detected, err := DetectChapterFormat(content)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+
+	var chapters []ImportedChapter
+	var err error
+
+	switch format {
+	case ChapterFormatMP4Chaps:
+		chapters, err = parseMP4Chaps(string(content))
+	case ChapterFormatFFMetadata:
+		chapters, err = parseFFMetadataChapters(string(content))
+	case ChapterFormatPodcastJSON:
+		chapters, err = parsePodcastChapters(content)
+	case ChapterFormatAudibleJSON:
+		chapters, err = parseAudibleChapters(content)
+	case ChapterFormatOGM:
+		chapters, err = parseOGMChapters(string(content))
+	default:
+		return nil, fmt.Errorf("unknown chapter format '%s', expected one of %v", format, chapterImportFormats)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", format, err)
+	}
+
+	if len(chapters) == 0 {
+		return nil, fmt.Errorf("no chapters found in %s", format)
+	}
+	return chapters, nil
+}
+
+// DetectChapterFormat guesses the format of a chapter file from its content.
+func DetectChapterFormat(content []byte) (string, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(content, []byte("\ufeff")))
+
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var probe map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &probe); err != nil {
+			return "", fmt.Errorf("chapter file looks like JSON but is invalid: %w", err)
+		}
+		_, hasContentMetadata := probe["content_metadata"]
+		_, hasChapterInfo := probe["chapter_info"]
+		if hasContentMetadata || hasChapterInfo || bytes.Contains(trimmed, []byte(`"start_offset_ms"`)) {
+			return ChapterFormatAudibleJSON, nil
+		}
+		if bytes.Contains(trimmed, []byte(`"startTime"`)) {
+			return ChapterFormatPodcastJSON, nil
+		}
+		return "", errors.New("could not detect the format of the JSON chapter file")
+	}
+
+	if bytes.HasPrefix(trimmed, []byte(";FFMETADATA")) {
+		return ChapterFormatFFMetadata, nil
+	}
+
+	firstLine, _, _ := strings.Cut(string(trimmed), "\n")
+	firstLine = strings.TrimSpace(firstLine)
+	switch {
+	case ogmLineRegex.MatchString(firstLine):
+		return ChapterFormatOGM, nil
+	case mp4ChapsLineRegex.MatchString(firstLine):
+		return ChapterFormatMP4Chaps, nil
+	}
+
+	return "", errors.New("could not detect the format of the chapter file, set chapters.format")
+}
+
+// parseMP4Chaps parses lines like "00:01:02.500 Title", as written by mp4chaps.
+func parseMP4Chaps(content string) ([]ImportedChapter, error) {
+	var chapters []ImportedChapter
+
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		match := mp4ChapsLineRegex.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d: expected '<hh:mm:ss.mmm> <title>', got '%s'", i+1, line)
+		}
+
+		start, err := parseTimestamp(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		chapters = append(chapters, ImportedChapter{Start: start, Title: strings.TrimSpace(match[2])})
+	}
+
+	return chapters, nil
+}
+
+// parseOGMChapters parses pairs of CHAPTERnn=hh:mm:ss.mmm and CHAPTERnnNAME=title.
+func parseOGMChapters(content string) ([]ImportedChapter, error) {
+	var chapters []ImportedChapter
+	byNumber := map[string]int{}
+
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		match := ogmLineRegex.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d: expected 'CHAPTERnn=' or 'CHAPTERnnNAME=', got '%s'", i+1, line)
+		}
+
+		number, isName, value := match[1], match[2] != "", match[3]
+
+		index, exists := byNumber[number]
+		if !isName {
+			if exists {
+				return nil, fmt.Errorf("line %d: chapter %s has two start times", i+1, number)
+			}
+			start, err := parseTimestamp(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			byNumber[number] = len(chapters)
+			chapters = append(chapters, ImportedChapter{Start: start})
+			continue
+		}
+
+		if !exists {
+			return nil, fmt.Errorf("line %d: name of chapter %s before its start time", i+1, number)
+		}
+		chapters[index].Title = value
+	}
+
+	return chapters, nil
+}
+
+// parseFFMetadataChapters parses the [CHAPTER] sections of an ffmetadata file.
+func parseFFMetadataChapters(content string) ([]ImportedChapter, error) {
+	var chapters []ImportedChapter
+	inChapter := false
+	timebase := 0.0
+
+	finish := func() error {
+		if !inChapter {
+			return nil
+		}
+		if timebase == 0 {
+			return fmt.Errorf("chapter %d has no TIMEBASE", len(chapters))
+		}
+		chapters[len(chapters)-1].Start *= timebase
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			inChapter = line == "[CHAPTER]"
+			if inChapter {
+				chapters = append(chapters, ImportedChapter{Start: -1})
+				timebase = 0
+			}
+			continue
+		}
+
+		if !inChapter {
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, "=")
+		switch strings.ToLower(key) {
+		case "timebase":
+			numerator, denominator, found := strings.Cut(value, "/")
+			n, err1 := strconv.ParseFloat(numerator, 64)
+			d, err2 := strconv.ParseFloat(denominator, 64)
+			if !found || err1 != nil || err2 != nil || d == 0 {
+				return nil, fmt.Errorf("line %d: invalid TIMEBASE '%s'", lineNumber, value)
+			}
+			timebase = n / d
+		case "start":
+			start, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid START '%s'", lineNumber, value)
+			}
+			chapters[len(chapters)-1].Start = start
+		case "title":
+			chapters[len(chapters)-1].Title = unescapeMetadataValue(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+
+	for i, chapter := range chapters {
+		if chapter.Start < 0 {
+			return nil, fmt.Errorf("chapter %d has no START", i)
+		}
+	}
+
+	return chapters, nil
+}
+
+// parsePodcastChapters parses Podcasting 2.0 chapters JSON. Chapters with
+// "toc": false are not part of the table of contents and skipped.
+func parsePodcastChapters(content []byte) ([]ImportedChapter, error) {
+	var file struct {
+		Chapters []struct {
+			StartTime *float64 `json:"startTime"`
+			Title     string   `json:"title"`
+			TOC       *bool    `json:"toc"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+
+	var chapters []ImportedChapter
+	for i, chapter := range file.Chapters {
+		if chapter.TOC != nil && !*chapter.TOC {
+			continue
+		}
+		if chapter.StartTime == nil {
+			return nil, fmt.Errorf("chapter %d has no startTime", i)
+		}
+		chapters = append(chapters, ImportedChapter{Start: *chapter.StartTime, Title: chapter.Title})
+	}
+
+	return chapters, nil
+}
+
+type audibleChapter struct {
+	StartOffsetMs *int64           `json:"start_offset_ms"`
+	Title         string           `json:"title"`
+	Chapters      []audibleChapter `json:"chapters"`
+}
+
+// parseAudibleChapters parses the chapter_info of Audible's content metadata.
+// Nested chapters are flattened, each following its parent.
+func parseAudibleChapters(content []byte) ([]ImportedChapter, error) {
+	type chapterInfo struct {
+		Chapters []audibleChapter `json:"chapters"`
+	}
+	var file struct {
+		ContentMetadata struct {
+			ChapterInfo chapterInfo `json:"chapter_info"`
+		} `json:"content_metadata"`
+		ChapterInfo chapterInfo      `json:"chapter_info"`
+		Chapters    []audibleChapter `json:"chapters"`
+	}
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+
+	var chapters []ImportedChapter
+	var flatten func(list []audibleChapter) error
+	flatten = func(list []audibleChapter) error {
+		for _, chapter := range list {
+			if chapter.StartOffsetMs == nil {
+				return fmt.Errorf("chapter '%s' has no start_offset_ms", chapter.Title)
+			}
+			chapters = append(chapters, ImportedChapter{Start: float64(*chapter.StartOffsetMs) / 1000, Title: chapter.Title})
+			if err := flatten(chapter.Chapters); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, list := range [][]audibleChapter{
+		file.ContentMetadata.ChapterInfo.Chapters, file.ChapterInfo.Chapters, file.Chapters,
+	} {
+		if err := flatten(list); err != nil {
+			return nil, err
+		}
+	}
+
+	return chapters, nil
+}
+
+// importedChapters turns chapters read from a file into chapters of the
+// project. The first chapter always starts at 0, every chapter has to start
+// after the previous one and before the end of the audiobook.
+func importedChapters(imported []ImportedChapter, duration float64, file string) ([]*Chapter, error) {
+	isSorted := slices.IsSortedFunc(imported, func(a, b ImportedChapter) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	if !isSorted {
+		return nil, errors.New("the chapters are not ordered by their start")
+	}
+
+	var chapters []*Chapter
+	for i, chapter := range imported {
+		if chapter.Start >= duration {
+			return nil, fmt.Errorf(
+				"chapter %d '%s' starts at %s, after the end of the audiobook at %s",
+				i, chapter.Title, formatTimestamp(chapter.Start), formatTimestamp(duration),
+			)
+		}
+		if i > 0 && chapter.Start == imported[i-1].Start {
+			return nil, fmt.Errorf("chapter %d '%s' starts at the same time as the previous chapter", i, chapter.Title)
+		}
+
+		start := chapter.Start
+		if i == 0 {
+			start = 0
+		}
+		end := duration
+		if i+1 < len(imported) {
+			end = imported[i+1].Start
+		}
+
+		var previous *Chapter
+		if len(chapters) > 0 {
+			previous = chapters[len(chapters)-1]
+		}
+		chapters = append(chapters, &Chapter{
+			title:           chapter.Title,
+			previousChapter: previous,
+			files:           []File{{Name: file, Duration: end - start}},
+			baseIndex:       i,
+		})
+	}
+
+	return chapters, nil
+}
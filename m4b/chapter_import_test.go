@@ -0,0 +1,151 @@
+package m4b_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+var importedBook = []m4b.ImportedChapter{
+	{Start: 0, Title: "Intro"},
+	{Start: 60, Title: "Intermezzo"},
+	{Start: 121.5, Title: "Kapitel 1"},
+}
+
+func TestReadChapterFile(t *testing.T) {
+	tests := []struct {
+		file   string
+		format string
+	}{
+		{"book.chapters.txt", "mp4chaps"},
+		{"book.ffmetadata", "ffmetadata"},
+		{"podcast.json", "podcast-json"},
+		{"audible.json", "audible-json"},
+		{"book.ogm.txt", "ogm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join("testdata", "chapters", "import", tt.file)
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			detected, err := m4b.DetectChapterFormat(content)
+			require.NoError(t, err)
+			require.Equal(t, tt.format, detected)
+
+			expected := importedBook
+			if tt.format == "ffmetadata" {
+				expected = append([]m4b.ImportedChapter{}, importedBook...)
+				expected[1].Title = "Intermezzo = Pause"
+			}
+
+			for _, format := range []string{"", tt.format} {
+				chapters, err := m4b.ReadChapterFile(path, format)
+				require.NoError(t, err)
+				require.Equal(t, expected, chapters)
+			}
+		})
+	}
+}
+
+func TestParseChapters_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		format  string
+		err     string
+	}{
+		{"undetectable", "Intro at 0:00\n", "", "could not detect the format"},
+		{"unknown format", "", "cue", "unknown chapter format 'cue'"},
+		{"empty", "", "mp4chaps", "no chapters found in mp4chaps"},
+		{"mp4chaps line", "00:00:00.000 Intro\nIntermezzo\n", "mp4chaps", "invalid mp4chaps: line 2"},
+		{"ogm name first", "CHAPTER01NAME=Intro\n", "ogm", "name of chapter 01 before its start time"},
+		{"ffmetadata timebase", ";FFMETADATA1\n[CHAPTER]\nSTART=0\ntitle=Intro\n", "ffmetadata", "chapter 1 has no TIMEBASE"},
+		{"podcast start", `{"chapters": [{"title": "Intro"}]}`, "podcast-json", "chapter 0 has no startTime"},
+		{"invalid json", `{"chapters": [}`, "", "looks like JSON but is invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m4b.ParseChapters([]byte(tt.content), tt.format)
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestChapters_SourceFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+		err      string
+	}{
+		{
+			name:     "imported",
+			content:  "00:00:00.000 Anfang\n00:03:00.250 Mitte\n",
+			expected: "CHAPTER0=00:00:00.000\nCHAPTER0NAME=Anfang\n\nCHAPTER1=00:03:00.250\nCHAPTER1NAME=Mitte",
+		},
+		{
+			name:     "first chapter starts at 0",
+			content:  "00:00:02.000 Anfang\n00:03:00.250 Mitte\n",
+			expected: "CHAPTER0=00:00:00.000\nCHAPTER0NAME=Anfang\n\nCHAPTER1=00:03:00.250\nCHAPTER1NAME=Mitte",
+		},
+		{
+			name:    "after the end",
+			content: "00:00:00.000 Anfang\n00:06:15.500 Zu spät\n",
+			err:     "chapter 1 'Zu spät' starts at 00:06:15.500, after the end of the audiobook at 00:06:15.500",
+		},
+		{
+			name:    "unordered",
+			content: "00:00:00.000 Anfang\n00:03:00.000 Mitte\n00:01:00.000 Früher\n",
+			err:     "the chapters are not ordered by their start",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "book.chapters.txt")
+			require.NoError(t, os.WriteFile(file, []byte(tt.content), 0644))
+
+			config := m4b.ProjectConfig{
+				ProjectPath: "/book",
+				Chapters:    m4b.ChaptersConfig{Source: "file", File: file},
+			}
+			require.NoError(t, config.Validate())
+
+			project, err := m4b.NewProjectWithDeps(config, fakeDeps(chapterData, chapterFiles))
+			require.NoError(t, err)
+
+			chapters, err := project.Chapters()
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, chapters)
+		})
+	}
+}
+
+func TestChaptersConfig_ValidateSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		chapters m4b.ChaptersConfig
+		err      string
+	}{
+		{"unknown source", m4b.ChaptersConfig{Source: "cue"}, "unknown source 'cue'"},
+		{"missing file", m4b.ChaptersConfig{Source: "file"}, "source file requires a file"},
+		{"unknown format", m4b.ChaptersConfig{Source: "file", File: "x", Format: "srt"}, "unknown format 'srt'"},
+		{"file without source", m4b.ChaptersConfig{File: "x"}, "file and format are only allowed with source file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.chapters.Validate(), tt.err)
+		})
+	}
+}
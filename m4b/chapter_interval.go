@@ -0,0 +1,101 @@
+package m4b
+
+import (
+	"fmt"
+	"math"
+)
+
+// validateInterval checks every and snap, which are only allowed with source
+// interval.
+func (c *ChaptersConfig) validateInterval() error {
+	if c.Source != ChapterSourceInterval {
+		if c.Every != "" || c.Snap != "" {
+			return fmt.Errorf("every and snap are only allowed with source %s", ChapterSourceInterval)
+		}
+		return nil
+	}
+
+	if c.Every == "" {
+		return fmt.Errorf("source %s requires every", ChapterSourceInterval)
+	}
+	every, err := parseSeconds(c.Every)
+	if err != nil || every <= 0 {
+		return fmt.Errorf("every '%s' is invalid, expected e.g. 10m or 600", c.Every)
+	}
+
+	if c.Snap != "" {
+		snap, err := parseSeconds(c.Snap)
+		if err != nil || snap < 0 {
+			return fmt.Errorf("snap '%s' is invalid, expected e.g. 30s or 30", c.Snap)
+		}
+	}
+	if c.Silence.TargetLength != 0 {
+		return fmt.Errorf("silence.targetLength is not allowed with source %s, use every", ChapterSourceInterval)
+	}
+	if c.Snap == "" && c.Silence != (SilenceConfig{}) {
+		return fmt.Errorf("silence is only allowed with source %s together with snap", ChapterSourceInterval)
+	}
+	return nil
+}
+
+// intervalBoundaries returns a boundary every Every seconds and the total
+// duration. With Snap, each boundary moves to the end of the closest silence
+// within Snap seconds, if there is one.
+func (p *Project) intervalBoundaries(tracks []Track) ([]float64, float64, error) {
+	// both are validated by Validate
+	every, _ := parseSeconds(p.Config.Chapters.Every)
+	snap := 0.0
+	if p.Config.Chapters.Snap != "" {
+		snap, _ = parseSeconds(p.Config.Chapters.Snap)
+	}
+
+	total := 0.0
+	var silences []float64
+
+	if snap > 0 {
+		var err error
+		silences, total, err = p.silenceBoundaries(tracks)
+		if err != nil {
+			return nil, 0, err
+		}
+	} else {
+		for _, track := range tracks {
+			_, duration, err := track.TitleAndDuration()
+			if err != nil {
+				return nil, 0, fmt.Errorf("could not read file data for file %s: %w", track.File, err)
+			}
+			total += duration
+		}
+	}
+
+	var boundaries []float64
+	previous := 0.0
+
+	for ideal := every; ideal < total; ideal += every {
+		boundary := snapBoundary(ideal, silences, previous, snap)
+		if boundary <= previous || boundary >= total {
+			continue
+		}
+		boundaries = append(boundaries, boundary)
+		previous = boundary
+	}
+
+	return boundaries, total, nil
+}
+
+// snapBoundary returns the candidate after previous closest to ideal, if it
+// is at most window away, otherwise ideal.
+func snapBoundary(ideal float64, candidates []float64, previous float64, window float64) float64 {
+	best := ideal
+	bestDistance := math.Inf(1)
+
+	for _, candidate := range candidates {
+		distance := math.Abs(candidate - ideal)
+		if candidate > previous && distance <= window && distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,70 @@
+package m4b_test
+
+import (
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChapters_SourceInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		chapters m4b.ChaptersConfig
+	}{
+		{name: "every", chapters: m4b.ChaptersConfig{Source: "interval", Every: "10m"}},
+		{name: "seconds", chapters: m4b.ChaptersConfig{Source: "interval", Every: "1000"}},
+		{
+			name: "snap",
+			chapters: m4b.ChaptersConfig{
+				Source:   "interval",
+				Every:    "10m",
+				Snap:     "30s",
+				Template: "Teil {{.Index}} ({{.Start}})",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := m4b.ProjectConfig{ProjectPath: "/book", Chapters: tt.chapters}
+			require.NoError(t, config.Validate())
+
+			project, err := m4b.NewProjectWithDeps(config, fakeDeps(silenceData, silenceFiles))
+			require.NoError(t, err)
+
+			chapters, err := project.Chapters()
+			require.NoError(t, err)
+			requireGolden(t, "chapters/interval-"+tt.name, chapters)
+		})
+	}
+}
+
+func TestChaptersConfig_ValidateInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		chapters m4b.ChaptersConfig
+		err      string
+	}{
+		{"valid snap with silence", m4b.ChaptersConfig{Source: "interval", Every: "10m", Snap: "30", Silence: m4b.SilenceConfig{Noise: "-40dB"}}, ""},
+		{"missing every", m4b.ChaptersConfig{Source: "interval"}, "source interval requires every"},
+		{"invalid every", m4b.ChaptersConfig{Source: "interval", Every: "often"}, "every 'often' is invalid"},
+		{"zero every", m4b.ChaptersConfig{Source: "interval", Every: "0s"}, "every '0s' is invalid"},
+		{"invalid snap", m4b.ChaptersConfig{Source: "interval", Every: "10m", Snap: "-5s"}, "snap '-5s' is invalid"},
+		{"every without source", m4b.ChaptersConfig{Every: "10m"}, "every and snap are only allowed with source interval"},
+		{"silence without snap", m4b.ChaptersConfig{Source: "interval", Every: "10m", Silence: m4b.SilenceConfig{MinLength: 1}}, "silence is only allowed with source interval together with snap"},
+		{"target length", m4b.ChaptersConfig{Source: "interval", Every: "10m", Snap: "30s", Silence: m4b.SilenceConfig{TargetLength: 600}}, "silence.targetLength is not allowed"},
+		{"grouping", m4b.ChaptersConfig{Source: "interval", Every: "10m", Grouping: "per-file"}, "title, grouping and embedded are not allowed with source interval"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.chapters.Validate()
+			if tt.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package m4b
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ChapterOverride changes the computed chapters where rules are not enough.
+// An override matches chapters by Index (the number of the chapter marker,
+// starting at 0) or by Title, and then renames, merges or drops them. An
+// override with Insert adds a chapter at a timestamp instead.
+//
+// Index and Title always refer to the chapters as computed before any
+// override is applied.
+type ChapterOverride struct {
+	Index  *int           `yaml:"index,omitempty"`
+	Title  string         `yaml:"title,omitempty"`
+	Rename string         `yaml:"rename,omitempty"`
+	Merge  bool           `yaml:"merge,omitempty"` // merge with the next chapter
+	Drop   bool           `yaml:"drop,omitempty"`  // the previous chapter continues instead
+	Insert *ChapterInsert `yaml:"insert,omitempty"`
+}
+
+// ChapterInsert is a chapter starting at a fixed timestamp, e.g. in the
+// middle of a file.
+type ChapterInsert struct {
+	At    string `yaml:"at"` // [[hh:]mm:]ss[.mmm]
+	Title string `yaml:"title"`
+}
+
+// Validate checks that the override matches chapters and has exactly one action.
+func (o *ChapterOverride) Validate() error {
+	actions := 0
+	for _, set := range []bool{o.Rename != "", o.Merge, o.Drop, o.Insert != nil} {
+		if set {
+			actions++
+		}
+	}
+	if actions != 1 {
+		return errors.New("override requires exactly one of rename, merge, drop or insert")
+	}
+
+	if o.Insert != nil {
+		if o.Index != nil || o.Title != "" {
+			return errors.New("insert does not match chapters, index and title are not allowed")
+		}
+		if o.Insert.Title == "" {
+			return errors.New("insert requires a title")
+		}
+		if _, err := parseTimestamp(o.Insert.At); err != nil {
+			return fmt.Errorf("insert at invalid: %w", err)
+		}
+		return nil
+	}
+
+	if (o.Index == nil) == (o.Title == "") {
+		return errors.New("override requires either index or title")
+	}
+	if o.Index != nil && *o.Index < 0 {
+		return fmt.Errorf("index %d is negative", *o.Index)
+	}
+	return nil
+}
+
+// String describes the override by what it matches and what it does.
+func (o ChapterOverride) String() string {
+	if o.Insert != nil {
+		return fmt.Sprintf("insert '%s' at %s", o.Insert.Title, o.Insert.At)
+	}
+
+	target := fmt.Sprintf("title '%s'", o.Title)
+	if o.Index != nil {
+		target = fmt.Sprintf("index %d", *o.Index)
+	}
+
+	switch {
+	case o.Merge:
+		return "merge " + target
+	case o.Drop:
+		return "drop " + target
+	default:
+		return fmt.Sprintf("rename %s to '%s'", target, o.Rename)
+	}
+}
+
+func (o *ChapterOverride) matches(chapter *Chapter, index int) bool {
+	if o.Index != nil {
+		return *o.Index == index
+	}
+	return o.Title == chapter.title
+}
+
+// applyChapterOverrides returns the chapters with the overrides applied.
+// Renames, merges and drops are applied first, inserts afterwards on the
+// resulting timeline. Every override has to match at least one chapter.
+func applyChapterOverrides(chapters []*Chapter, overrides []ChapterOverride) ([]*Chapter, error) {
+	if len(overrides) == 0 {
+		return chapters, nil
+	}
+
+	merge := make([]bool, len(chapters))
+	drop := make([]bool, len(chapters))
+	renames := make([]string, len(chapters))
+	var inserts []ChapterInsert
+
+	for _, override := range overrides {
+		if override.Insert != nil {
+			inserts = append(inserts, *override.Insert)
+			continue
+		}
+
+		matched := false
+		for i, chapter := range chapters {
+			if !override.matches(chapter, i) {
+				continue
+			}
+			matched = true
+
+			switch {
+			case override.Merge:
+				if i == len(chapters)-1 {
+					return nil, fmt.Errorf("override '%s': the last chapter has no next chapter to merge with", override)
+				}
+				merge[i] = true
+			case override.Drop:
+				drop[i] = true
+			default:
+				renames[i] = override.Rename
+			}
+		}
+
+		if !matched {
+			return nil, fmt.Errorf("override '%s' matches no chapter", override)
+		}
+	}
+
+	var result []*Chapter
+	var dropped []File // files of dropped chapters without a previous chapter
+	var droppedTitles []string
+	mergeNext := false
+
+	for i, chapter := range chapters {
+		if drop[i] && (merge[i] || renames[i] != "") {
+			return nil, fmt.Errorf("chapter %d '%s' is dropped and overridden otherwise", i, chapter.title)
+		}
+
+		var last *Chapter
+		if len(result) > 0 {
+			last = result[len(result)-1]
+		}
+
+		switch {
+		case drop[i] && last != nil:
+			last.files = append(last.files, chapter.files...)
+			last.overrides = append(last.overrides, fmt.Sprintf("continues over dropped '%s'", chapter.title))
+			continue
+		case drop[i]:
+			dropped = append(dropped, chapter.files...)
+			droppedTitles = append(droppedTitles, chapter.title)
+			continue
+		case mergeNext && renames[i] != "":
+			return nil, fmt.Errorf("chapter %d '%s' is merged into the previous chapter and renamed", i, chapter.title)
+		case mergeNext && last != nil:
+			last.files = append(last.files, chapter.files...)
+			last.overrides = append(last.overrides, fmt.Sprintf("merged with '%s'", chapter.title))
+			mergeNext = merge[i]
+			continue
+		}
+
+		next := &Chapter{
+			title:     chapter.title,
+			files:     slices.Clone(chapter.files),
+			overrides: chapter.overrides,
+			baseIndex: chapter.baseIndex,
+		}
+		if renames[i] != "" {
+			next.title = renames[i]
+			next.overrides = append(next.overrides, fmt.Sprintf("renamed from '%s'", chapter.title))
+		}
+		if len(dropped) > 0 {
+			next.files = append(dropped, next.files...)
+			for _, title := range droppedTitles {
+				next.overrides = append(next.overrides, fmt.Sprintf("starts with dropped '%s'", title))
+			}
+			dropped, droppedTitles = nil, nil
+		}
+
+		result = append(result, next)
+		mergeNext = merge[i]
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("overrides drop all chapters")
+	}
+	linkChapters(result)
+
+	for _, insert := range inserts {
+		var err error
+		result, err = insertChapter(result, insert)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// insertChapter splits the chapter containing the timestamp of insert,
+// splitting the file at the timestamp if necessary.
+func insertChapter(chapters []*Chapter, insert ChapterInsert) ([]*Chapter, error) {
+	at, err := parseTimestamp(insert.At)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, chapter := range chapters {
+		start := chapter.offset()
+		end := start + chapter.duration()
+		if at < start || at >= end {
+			continue
+		}
+		if at == start {
+			return nil, fmt.Errorf("insert '%s': chapter %d '%s' already starts at %s", insert.Title, i, chapter.title, insert.At)
+		}
+
+		var before, after []File
+		position := start
+		for _, file := range chapter.files {
+			switch {
+			case position+file.Duration <= at:
+				before = append(before, file)
+			case position >= at:
+				after = append(after, file)
+			default:
+				before = append(before, File{Name: file.Name, Duration: at - position})
+				after = append(after, File{Name: file.Name, Duration: position + file.Duration - at})
+			}
+			position += file.Duration
+		}
+
+		chapter.files = before
+		inserted := &Chapter{
+			title:     insert.Title,
+			files:     after,
+			overrides: []string{fmt.Sprintf("inserted at %s", insert.At)},
+			baseIndex: -1,
+		}
+
+		chapters = append(chapters[:i+1], append([]*Chapter{inserted}, chapters[i+1:]...)...)
+		linkChapters(chapters)
+		return chapters, nil
+	}
+
+	return nil, fmt.Errorf("insert '%s': %s is beyond the end of the audiobook", insert.Title, insert.At)
+}
+
+// linkChapters sets the previous chapter of every chapter, so the offsets
+// follow the order of the slice.
+func linkChapters(chapters []*Chapter) {
+	for i, chapter := range chapters {
+		chapter.previousChapter = nil
+		if i > 0 {
+			chapter.previousChapter = chapters[i-1]
+		}
+	}
+}
+
+// parseTimestamp parses [[hh:]mm:]ss[.mmm] into seconds.
+func parseTimestamp(timestamp string) (float64, error) {
+	parts := strings.Split(timestamp, ":")
+	if timestamp == "" || len(parts) > 3 {
+		return 0, fmt.Errorf("timestamp '%s' is not in the format [[hh:]mm:]ss[.mmm]", timestamp)
+	}
+
+	seconds := 0.0
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(part, 64)
+		isLast := i == len(parts)-1
+		if err != nil || value < 0 || math.IsInf(value, 0) || (!isLast && strings.Contains(part, ".")) {
+			return 0, fmt.Errorf("timestamp '%s' is not in the format [[hh:]mm:]ss[.mmm]", timestamp)
+		}
+		seconds = seconds*60 + value
+	}
+	return seconds, nil
+}
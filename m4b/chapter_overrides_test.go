@@ -0,0 +1,126 @@
+package m4b_test
+
+import (
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+func chapterIndex(i int) *int {
+	return &i
+}
+
+func TestChapters_Overrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides []m4b.ChapterOverride
+	}{
+		{name: "rename-by-index", overrides: []m4b.ChapterOverride{{Index: chapterIndex(0), Rename: "Prolog"}}},
+		{name: "rename-by-title", overrides: []m4b.ChapterOverride{{Title: "Intermezzo", Rename: "Pause"}}},
+		{name: "merge", overrides: []m4b.ChapterOverride{{Index: chapterIndex(1), Merge: true}, {Index: chapterIndex(2), Merge: true}}},
+		{name: "drop", overrides: []m4b.ChapterOverride{{Index: chapterIndex(0), Drop: true}, {Index: chapterIndex(2), Drop: true}}},
+		{
+			name: "insert",
+			overrides: []m4b.ChapterOverride{
+				{Insert: &m4b.ChapterInsert{At: "3:00.250", Title: "Mitten in Kapitel 1"}},
+				{Index: chapterIndex(3), Rename: "Finale"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := m4b.ProjectConfig{
+				ProjectPath: "/book",
+				HasChapters: true,
+				Chapters:    m4b.ChaptersConfig{Overrides: tt.overrides},
+			}
+			require.NoError(t, config.Validate())
+
+			project, err := m4b.NewProjectWithDeps(config, fakeDeps(chapterData, chapterFiles))
+			require.NoError(t, err)
+
+			chapters, err := project.Chapters()
+			require.NoError(t, err)
+			requireGolden(t, "chapters/override-"+tt.name, chapters)
+		})
+	}
+}
+
+func TestChapters_OverridesMarked(t *testing.T) {
+	config := m4b.ProjectConfig{
+		ProjectPath: "/book",
+		Chapters: m4b.ChaptersConfig{Overrides: []m4b.ChapterOverride{
+			{Index: chapterIndex(0), Rename: "Prolog"},
+			{Index: chapterIndex(2), Merge: true},
+		}},
+	}
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(chapterData, chapterFiles))
+	require.NoError(t, err)
+
+	chapters, err := project.ChapterList()
+	require.NoError(t, err)
+	require.Len(t, chapters, 3)
+	require.Equal(t, []string{"renamed from 'Intro'"}, chapters[0].Overrides())
+	require.Empty(t, chapters[1].Overrides())
+	require.Equal(t, "Kapitel 1", chapters[2].Title())
+	require.Equal(t, []string{"merged with 'Intermezzo'"}, chapters[2].Overrides())
+	require.Equal(t, 121.5, chapters[2].Offset())
+}
+
+func TestChapters_OverrideErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides []m4b.ChapterOverride
+		err       string
+	}{
+		{"no match", []m4b.ChapterOverride{{Title: "Epilog", Rename: "x"}}, "override 'rename title 'Epilog' to 'x'' matches no chapter"},
+		{"merge last", []m4b.ChapterOverride{{Index: chapterIndex(3), Merge: true}}, "the last chapter has no next chapter"},
+		{"drop all", []m4b.ChapterOverride{{Title: "Intro", Drop: true}, {Title: "Intermezzo", Drop: true}, {Title: "Kapitel 1", Drop: true}}, "overrides drop all chapters"},
+		{"drop and rename", []m4b.ChapterOverride{{Index: chapterIndex(1), Drop: true}, {Index: chapterIndex(1), Rename: "x"}}, "is dropped and overridden otherwise"},
+		{"insert at chapter start", []m4b.ChapterOverride{{Insert: &m4b.ChapterInsert{At: "1:00", Title: "x"}}}, "chapter 1 'Intermezzo' already starts at 1:00"},
+		{"insert after end", []m4b.ChapterOverride{{Insert: &m4b.ChapterInsert{At: "1:00:00", Title: "x"}}}, "beyond the end of the audiobook"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := m4b.ProjectConfig{ProjectPath: "/book", Chapters: m4b.ChaptersConfig{Overrides: tt.overrides}}
+			project, err := m4b.NewProjectWithDeps(config, fakeDeps(chapterData, chapterFiles))
+			require.NoError(t, err)
+
+			_, err = project.Chapters()
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestChapterOverride_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		override m4b.ChapterOverride
+		err      string
+	}{
+		{"valid rename", m4b.ChapterOverride{Index: chapterIndex(0), Rename: "x"}, ""},
+		{"valid insert", m4b.ChapterOverride{Insert: &m4b.ChapterInsert{At: "01:02:03.5", Title: "x"}}, ""},
+		{"no action", m4b.ChapterOverride{Index: chapterIndex(0)}, "exactly one of rename, merge, drop or insert"},
+		{"two actions", m4b.ChapterOverride{Index: chapterIndex(0), Merge: true, Drop: true}, "exactly one of rename, merge, drop or insert"},
+		{"no target", m4b.ChapterOverride{Drop: true}, "either index or title"},
+		{"two targets", m4b.ChapterOverride{Index: chapterIndex(0), Title: "x", Drop: true}, "either index or title"},
+		{"negative index", m4b.ChapterOverride{Index: chapterIndex(-1), Drop: true}, "index -1 is negative"},
+		{"insert with target", m4b.ChapterOverride{Title: "x", Insert: &m4b.ChapterInsert{At: "1", Title: "x"}}, "index and title are not allowed"},
+		{"insert without title", m4b.ChapterOverride{Insert: &m4b.ChapterInsert{At: "1"}}, "insert requires a title"},
+		{"invalid timestamp", m4b.ChapterOverride{Insert: &m4b.ChapterInsert{At: "1.5:00", Title: "x"}}, "timestamp '1.5:00' is not in the format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.override.Validate()
+			if tt.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
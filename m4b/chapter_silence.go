@@ -0,0 +1,180 @@
+package m4b
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+)
+
+const (
+	defaultSilenceNoise     = "-30dB"
+	defaultSilenceMinLength = 2.0
+)
+
+// noiseRegex matches the noise thresholds of silencedetect, in dB or as
+// amplitude ratio.
+var noiseRegex = regexp.MustCompile(`^(-?\d+(\.\d+)?dB|\d+(\.\d+)?)$`)
+
+// Silence is a silent part of an audio file, in seconds.
+type Silence struct {
+	Start float64
+	End   float64
+}
+
+// SilenceConfig configures the detection of chapter boundaries at silences,
+// used by source silence.
+type SilenceConfig struct {
+	// Noise is the volume below which audio counts as silence, e.g. -30dB
+	// (the default) or an amplitude ratio like 0.001.
+	Noise string `yaml:"noise,omitempty"`
+	// MinLength is the minimum duration of a silence in seconds, 2 by default.
+	MinLength float64 `yaml:"minLength,omitempty"`
+	// TargetLength is the preferred length of a chapter in seconds. With it,
+	// only the silence closest to every target length becomes a chapter
+	// boundary. Without, every silence does.
+	TargetLength float64 `yaml:"targetLength,omitempty"`
+}
+
+// Validate checks the noise threshold and the lengths.
+func (c *SilenceConfig) Validate() error {
+	if c.Noise != "" && !noiseRegex.MatchString(c.Noise) {
+		return fmt.Errorf("noise '%s' is invalid, expected e.g. -30dB or 0.001", c.Noise)
+	}
+	if c.MinLength < 0 {
+		return fmt.Errorf("minLength %g is negative", c.MinLength)
+	}
+	if c.TargetLength < 0 {
+		return fmt.Errorf("targetLength %g is negative", c.TargetLength)
+	}
+	return nil
+}
+
+func (c *SilenceConfig) noise() string {
+	if c.Noise == "" {
+		return defaultSilenceNoise
+	}
+	return c.Noise
+}
+
+func (c *SilenceConfig) minLength() float64 {
+	if c.MinLength == 0 {
+		return defaultSilenceMinLength
+	}
+	return c.MinLength
+}
+
+// silenceBoundaries returns the ends of all silences within the tracks,
+// shifted by the offsets of the tracks, and the total duration. A chapter
+// starting at the end of a silence starts right with the audio.
+func (p *Project) silenceBoundaries(tracks []Track) ([]float64, float64, error) {
+	var boundaries []float64
+	offset := 0.0
+
+	for _, track := range tracks {
+		_, duration, err := track.TitleAndDuration()
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not read file data for file %s: %w", track.File, err)
+		}
+
+		silences, err := p.detectSilence(track.File)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// silences of cue sheet segments are relative to the whole file
+		start, _, isSegment := track.Segment()
+		if !isSegment {
+			start = 0
+		}
+
+		for _, silence := range silences {
+			position := silence.End - start
+			if position > 0 && position < duration {
+				boundaries = append(boundaries, offset+position)
+			}
+		}
+
+		offset += duration
+	}
+
+	slices.Sort(boundaries)
+	return slices.Compact(boundaries), offset, nil
+}
+
+// detectSilence returns the silences of the file. Every file is only
+// detected once, as the tracks of a cue sheet share the same file.
+func (p *Project) detectSilence(file string) ([]Silence, error) {
+	if silences, ok := p.silences[file]; ok {
+		return silences, nil
+	}
+
+	config := p.Config.Chapters.Silence
+	silences, err := p.deps.AudioProcessor.DetectSilence(file, config.noise(), config.minLength())
+	if err != nil {
+		return nil, err
+	}
+
+	if p.silences == nil {
+		p.silences = map[string][]Silence{}
+	}
+	p.silences[file] = silences
+	return silences, nil
+}
+
+// pickBoundaries picks the chapter boundaries among the candidates. Without
+// target, all candidates are used. Otherwise, starting at 0, the candidate
+// closest to the previous boundary plus target is picked, until the rest
+// fits into one and a half chapters.
+func pickBoundaries(candidates []float64, total float64, target float64) []float64 {
+	if target <= 0 {
+		return candidates
+	}
+
+	var picked []float64
+	previous := 0.0
+
+	for total-previous >= 1.5*target {
+		ideal := previous + target
+
+		best := math.NaN()
+		for _, candidate := range candidates {
+			if candidate <= previous {
+				continue
+			}
+			if math.IsNaN(best) || math.Abs(candidate-ideal) < math.Abs(best-ideal) {
+				best = candidate
+			}
+		}
+
+		if math.IsNaN(best) {
+			break
+		}
+		picked = append(picked, best)
+		previous = best
+	}
+
+	return picked
+}
+
+// generatedChapters builds chapters starting at 0 and at each of the
+// boundaries, titled by the chapters template.
+func (p *Project) generatedChapters(tracks []Track, boundaries []float64, total float64) ([]*Chapter, error) {
+	if len(tracks) == 0 {
+		return nil, errors.New("no tracks to generate chapters for")
+	}
+
+	starts := append([]float64{0}, boundaries...)
+	imported := make([]ImportedChapter, 0, len(starts))
+
+	for i, start := range starts {
+		title, err := p.Config.Chapters.generatedTitle(&tracks[0], i+1, start)
+		if err != nil {
+			return nil, err
+		}
+		imported = append(imported, ImportedChapter{Start: start, Title: title})
+	}
+
+	return importedChapters(imported, total, tracks[0].File)
+}
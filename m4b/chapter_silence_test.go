@@ -0,0 +1,154 @@
+package m4b_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+var silenceFiles = []string{"/book/book.mp3"}
+
+var silenceData = map[string]m4b.FileData{
+	"/book/book.mp3": {
+		Title:    "Das Buch",
+		Duration: 3600,
+		Metadata: ";FFMETADATA1\ntitle=Das Buch\nalbum=Das Buch",
+		Silences: []m4b.Silence{
+			{Start: 0, End: 1.5},
+			{Start: 290, End: 293},
+			{Start: 601, End: 603.5},
+			{Start: 1180, End: 1182},
+			{Start: 1500, End: 1502.25},
+			{Start: 1790, End: 1795},
+			{Start: 2410, End: 2412},
+			{Start: 3598, End: 3600},
+		},
+	},
+}
+
+func TestChapters_SourceSilence(t *testing.T) {
+	tests := []struct {
+		name     string
+		chapters m4b.ChaptersConfig
+	}{
+		{name: "every-gap", chapters: m4b.ChaptersConfig{Source: "silence"}},
+		{
+			name: "target-length",
+			chapters: m4b.ChaptersConfig{
+				Source:   "silence",
+				Silence:  m4b.SilenceConfig{TargetLength: 900},
+				Template: `{{.album}}, Kapitel {{printf "%02d" .Index}} ({{.Start}})`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := m4b.ProjectConfig{ProjectPath: "/book", Chapters: tt.chapters}
+			require.NoError(t, config.Validate())
+
+			project, err := m4b.NewProjectWithDeps(config, fakeDeps(silenceData, silenceFiles))
+			require.NoError(t, err)
+
+			chapters, err := project.Chapters()
+			require.NoError(t, err)
+			requireGolden(t, "chapters/silence-"+tt.name, chapters)
+		})
+	}
+}
+
+func TestChapters_SourceSilenceNoGaps(t *testing.T) {
+	data := map[string]m4b.FileData{"/book/book.mp3": {Title: "Das Buch", Duration: 3600, Metadata: ";FFMETADATA1"}}
+	config := m4b.ProjectConfig{ProjectPath: "/book", Chapters: m4b.ChaptersConfig{Source: "silence"}}
+
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, silenceFiles))
+	require.NoError(t, err)
+
+	chapters, err := project.Chapters()
+	require.NoError(t, err)
+	require.Equal(t, "CHAPTER0=00:00:00.000\nCHAPTER0NAME=Chapter 1", chapters)
+}
+
+// countingAudioProcessor counts the silence detections per file
+type countingAudioProcessor struct {
+	*m4b.NullAudioProcessor
+	detections map[string]int
+}
+
+func (p *countingAudioProcessor) DetectSilence(file string, noise string, minLength float64) ([]m4b.Silence, error) {
+	p.detections[file]++
+	return p.NullAudioProcessor.DetectSilence(file, noise, minLength)
+}
+
+func TestChapters_SourceSilenceDetectsEachFileOnce(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rip.cue"), []byte(testCue), 0644))
+
+	flac := filepath.Join(dir, "Der Superpapagei.flac")
+	data := map[string]m4b.FileData{
+		flac: {
+			Title:    "Der Superpapagei",
+			Duration: 900,
+			Metadata: ";FFMETADATA1\nalbum=Superpapagei",
+			Silences: []m4b.Silence{{Start: 150, End: 152}, {Start: 450, End: 451}},
+		},
+	}
+
+	deps := fakeDeps(data, []string{flac})
+	processor := &countingAudioProcessor{NullAudioProcessor: &m4b.NullAudioProcessor{Data: data}, detections: map[string]int{}}
+	deps.AudioProcessor = processor
+	deps.TrackFactory = &m4b.FFmpegTrackFactory{AudioProcessor: processor}
+
+	config := m4b.ProjectConfig{ProjectPath: dir, Chapters: m4b.ChaptersConfig{Source: "silence"}}
+	project, err := m4b.NewProjectWithDeps(config, deps)
+	require.NoError(t, err)
+
+	tracks, err := project.Tracks()
+	require.NoError(t, err)
+	require.Len(t, tracks, 3)
+
+	chapters, err := project.Chapters()
+	require.NoError(t, err)
+	require.Equal(t, `CHAPTER0=00:00:00.000
+CHAPTER0NAME=Chapter 1
+
+CHAPTER1=00:02:32.000
+CHAPTER1NAME=Chapter 2
+
+CHAPTER2=00:07:31.000
+CHAPTER2NAME=Chapter 3`, chapters)
+
+	_, err = project.ChapterTable()
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{flac: 1}, processor.detections)
+}
+
+func TestChaptersConfig_ValidateSilence(t *testing.T) {
+	tests := []struct {
+		name     string
+		chapters m4b.ChaptersConfig
+		err      string
+	}{
+		{"valid ratio", m4b.ChaptersConfig{Source: "silence", Silence: m4b.SilenceConfig{Noise: "0.001"}}, ""},
+		{"invalid noise", m4b.ChaptersConfig{Source: "silence", Silence: m4b.SilenceConfig{Noise: "loud"}}, "noise 'loud' is invalid"},
+		{"negative min length", m4b.ChaptersConfig{Source: "silence", Silence: m4b.SilenceConfig{MinLength: -1}}, "minLength -1 is negative"},
+		{"negative target", m4b.ChaptersConfig{Source: "silence", Silence: m4b.SilenceConfig{TargetLength: -1}}, "targetLength -1 is negative"},
+		{"silence without source", m4b.ChaptersConfig{Silence: m4b.SilenceConfig{MinLength: 1}}, "silence is only allowed with sources silence and interval"},
+		{"title", m4b.ChaptersConfig{Source: "silence", Title: "filename"}, "title, grouping and embedded are not allowed with source silence"},
+		{"invalid template", m4b.ChaptersConfig{Source: "silence", Template: "{{.Index"}, "template '{{.Index' is invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.chapters.Validate()
+			if tt.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package m4b
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Chapter grouping modes, used in ChaptersConfig.Grouping
+const (
+	GroupingContiguous   = "contiguous"
+	GroupingPerFile      = "per-file"
+	GroupingPerDisc      = "per-disc"
+	GroupingPerDirectory = "per-directory"
+	GroupingByTagPrefix  = "by-tag:"
+)
+
+var groupingModes = []string{GroupingContiguous, GroupingPerFile, GroupingPerDisc, GroupingPerDirectory}
+
+// Chapter sources, used in ChaptersConfig.Source
+const (
+	ChapterSourceTracks   = "tracks"
+	ChapterSourceFile     = "file"
+	ChapterSourceSilence  = "silence"
+	ChapterSourceInterval = "interval"
+)
+
+var chapterSources = []string{ChapterSourceTracks, ChapterSourceFile, ChapterSourceSilence, ChapterSourceInterval}
+
+// generatedChapterSources are the sources generating chapters by time, which
+// are titled by the template.
+var generatedChapterSources = []string{ChapterSourceSilence, ChapterSourceInterval}
+
+// defaultGeneratedTemplate titles generated chapters if there is no template.
+const defaultGeneratedTemplate = "Chapter {{.Index}}"
+
+// Chapter title sources, used in ChaptersConfig.Title
+const (
+	TitleFromTag       = "tag"
+	TitleFromFilename  = "filename"
+	TitleFromDirectory = "directory"
+	TitleFromTemplate  = "template"
+	TitleFromTagPrefix = "tag:"
+)
+
+var titleSources = []string{TitleFromTag, TitleFromFilename, TitleFromDirectory, TitleFromTemplate}
+
+// ChaptersConfig configures how chapters are built from the tracks.
+type ChaptersConfig struct {
+	// Source is where the chapters come from: tracks (the default) builds them
+	// from the tracks, file reads them from File instead, silence places
+	// them at silences, see Silence, and interval places them every Every.
+	Source string `yaml:"source,omitempty"`
+	// File is the chapter file for source file, relative to the project.
+	File string `yaml:"file,omitempty"`
+	// Format of File: mp4chaps, ffmetadata, podcast-json, audible-json or
+	// ogm. Detected from the content if empty.
+	Format string `yaml:"format,omitempty"`
+	// Grouping decides which consecutive tracks form one chapter:
+	// contiguous (the default) starts a new chapter whenever the chapter title
+	// changes, per-file makes every track a chapter, per-disc, per-directory and
+	// by-tag:<name> start a new chapter whenever the disc, the directory or the
+	// value of the tag changes. Chapters are named after their first track.
+	Grouping string `yaml:"grouping,omitempty"`
+	// Title is where the chapter titles come from before the chapter rules
+	// are applied: tag (the title tag after the metadata rules), tag:<name>,
+	// filename, directory or template. Empty uses the title as read from the
+	// file, without metadata rules.
+	Title string `yaml:"title,omitempty"`
+	// Template is a Go template over the processed tags of the first track of
+	// the chapter, plus .Index (the chapter number, starting at 1), .Disc and
+	// .Track. Used with title: template. For generated chapters, e.g. source
+	// silence, the tags are those of the first track and .Start is the start
+	// of the chapter as hh:mm:ss.
+	Template string `yaml:"template,omitempty"`
+	// Every is the length of the chapters of source interval, as Go duration
+	// (10m, 1h30m) or plain seconds.
+	Every string `yaml:"every,omitempty"`
+	// Snap moves the chapters of source interval to the end of the closest
+	// silence at most this far away, detected as configured by Silence.
+	Snap string `yaml:"snap,omitempty"`
+	// Silence configures source silence and the snapping of source interval.
+	Silence SilenceConfig `yaml:"silence,omitempty"`
+	// Embedded decides what happens to chapters embedded in the input files:
+	// ignore (the default), flatten replaces the chapter of the file by its
+	// embedded chapters, nested prefixes them with the title of the chapter.
+	Embedded string `yaml:"embedded,omitempty"`
+	// Overrides rename, merge, drop or insert single chapters.
+	Overrides []ChapterOverride `yaml:"overrides,omitempty"`
+}
+
+// Validate checks the source, the grouping mode, the title source and the overrides.
+func (c *ChaptersConfig) Validate() error {
+	if err := c.validateSource(); err != nil {
+		return err
+	}
+	if err := c.validateGrouping(); err != nil {
+		return err
+	}
+	if err := c.validateTitle(); err != nil {
+		return err
+	}
+
+	for _, override := range c.Overrides {
+		if err := override.Validate(); err != nil {
+			return fmt.Errorf("override '%s' invalid: %w", override, err)
+		}
+	}
+	return nil
+}
+
+func (c *ChaptersConfig) validateSource() error {
+	if c.Source != "" && !slices.Contains(chapterSources, c.Source) {
+		return fmt.Errorf("unknown source '%s', expected one of %v", c.Source, chapterSources)
+	}
+
+	if err := validateEmbedded(c.Embedded); err != nil {
+		return err
+	}
+
+	if err := c.Silence.Validate(); err != nil {
+		return fmt.Errorf("silence invalid: %w", err)
+	}
+	if err := c.validateInterval(); err != nil {
+		return err
+	}
+	if c.Source != ChapterSourceSilence && c.Source != ChapterSourceInterval && c.Silence != (SilenceConfig{}) {
+		return fmt.Errorf("silence is only allowed with sources %s and %s", ChapterSourceSilence, ChapterSourceInterval)
+	}
+
+	if c.isGenerated() {
+		if c.Title != "" || c.Grouping != "" || (c.Embedded != "" && c.Embedded != EmbeddedIgnore) {
+			return fmt.Errorf("title, grouping and embedded are not allowed with source %s, use template", c.Source)
+		}
+	}
+
+	if c.Source != ChapterSourceFile {
+		if c.File != "" || c.Format != "" {
+			return fmt.Errorf("file and format are only allowed with source %s", ChapterSourceFile)
+		}
+		return nil
+	}
+
+	if c.Embedded != "" && c.Embedded != EmbeddedIgnore {
+		return fmt.Errorf("embedded is not allowed with source %s", ChapterSourceFile)
+	}
+
+	if c.File == "" {
+		return fmt.Errorf("source %s requires a file", ChapterSourceFile)
+	}
+	if c.Format != "" && !slices.Contains(chapterImportFormats, c.Format) {
+		return fmt.Errorf("unknown format '%s', expected one of %v", c.Format, chapterImportFormats)
+	}
+	return nil
+}
+
+func (c *ChaptersConfig) validateGrouping() error {
+	if c.Grouping == "" || slices.Contains(groupingModes, c.Grouping) {
+		return nil
+	}
+
+	if tag, isByTag := strings.CutPrefix(c.Grouping, GroupingByTagPrefix); isByTag {
+		if tag == "" {
+			return fmt.Errorf("grouping %s requires a tag name", GroupingByTagPrefix)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown grouping '%s', expected one of %v or %s<name>", c.Grouping, groupingModes, GroupingByTagPrefix)
+}
+
+func (c *ChaptersConfig) validateTitle() error {
+	if c.Title == TitleFromTemplate {
+		if c.Template == "" {
+			return fmt.Errorf("title %s requires a template", TitleFromTemplate)
+		}
+		_, err := c.template()
+		return err
+	}
+
+	if c.isGenerated() {
+		if c.Template == "" {
+			return nil
+		}
+		_, err := c.template()
+		return err
+	}
+
+	if c.Template != "" {
+		return fmt.Errorf("template is only allowed with title %s", TitleFromTemplate)
+	}
+
+	if c.Title == "" || slices.Contains(titleSources, c.Title) {
+		return nil
+	}
+
+	if tag, isTag := strings.CutPrefix(c.Title, TitleFromTagPrefix); isTag {
+		if tag == "" {
+			return fmt.Errorf("title %s requires a tag name", TitleFromTagPrefix)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown title '%s', expected one of %v or %s<name>", c.Title, titleSources, TitleFromTagPrefix)
+}
+
+// isGenerated reports whether the chapters are generated by time instead of
+// built from the tracks or read from a file.
+func (c *ChaptersConfig) isGenerated() bool {
+	return slices.Contains(generatedChapterSources, c.Source)
+}
+
+func (c *ChaptersConfig) template() (*template.Template, error) {
+	text := c.Template
+	if text == "" && c.isGenerated() {
+		text = defaultGeneratedTemplate
+	}
+
+	tmpl, err := template.New("chapter").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template '%s' is invalid: %w", text, err)
+	}
+	return tmpl, nil
+}
+
+// titleDependsOnIndex reports whether the title of a track depends on the
+// number of the chapter it starts.
+func (c *ChaptersConfig) titleDependsOnIndex() bool {
+	return c.Title == TitleFromTemplate
+}
+
+// title returns the title of the track according to the title source, before
+// the chapter rules are applied. index is the number of the chapter, starting
+// at 1, and only used by templates.
+func (c *ChaptersConfig) title(track *Track, index int) (string, error) {
+	switch c.Title {
+	case TitleFromFilename:
+		base := filepath.Base(track.File)
+		return strings.TrimSuffix(base, filepath.Ext(base)), nil
+	case TitleFromDirectory:
+		return filepath.Base(filepath.Dir(track.File)), nil
+	case TitleFromTemplate:
+		return c.renderTemplate(track, index)
+	case "":
+		title, _, err := track.TitleAndDuration()
+		return title, err
+	}
+
+	tag := "title"
+	if name, isTag := strings.CutPrefix(c.Title, TitleFromTagPrefix); isTag {
+		tag = strings.ToLower(name)
+	}
+
+	tags, _, err := track.Metadata()
+	if err != nil {
+		return "", err
+	}
+
+	return tags[tag], nil
+}
+
+func (c *ChaptersConfig) renderTemplate(track *Track, index int) (string, error) {
+	data, err := templateData(track)
+	if err != nil {
+		return "", err
+	}
+	data["Index"] = index
+	data["Disc"], _ = track.DiscNumber()
+	data["Track"], _ = track.TrackNumber()
+
+	return c.executeTemplate(data, track)
+}
+
+// generatedTitle returns the title of a generated chapter starting at start,
+// with track being the first track of the book.
+func (c *ChaptersConfig) generatedTitle(track *Track, index int, start float64) (string, error) {
+	data, err := templateData(track)
+	if err != nil {
+		return "", err
+	}
+	data["Index"] = index
+	data["Start"] = formatTimestamp(start)[:8]
+
+	return c.executeTemplate(data, track)
+}
+
+// templateData returns the processed tags of the track for templates.
+func templateData(track *Track) (map[string]any, error) {
+	tags, _, err := track.Metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]any, len(tags)+3)
+	for tag, value := range tags {
+		data[tag] = value
+	}
+	return data, nil
+}
+
+func (c *ChaptersConfig) executeTemplate(data map[string]any, track *Track) (string, error) {
+	tmpl, err := c.template()
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err = tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("could not render chapter title of %s: %w", track.File, err)
+	}
+	return sb.String(), nil
+}
+
+// groupKey returns the key of the track for the grouping. Consecutive tracks
+// with the same key belong to the same chapter.
+func (c *ChaptersConfig) groupKey(track *Track, chapterName string, index int) (string, error) {
+	switch c.Grouping {
+	case GroupingPerFile:
+		return strconv.Itoa(index), nil
+	case GroupingPerDisc:
+		disc, _ := track.DiscNumber()
+		return strconv.Itoa(disc), nil
+	case GroupingPerDirectory:
+		return path.Dir(track.RelativePath()), nil
+	case "", GroupingContiguous:
+		return chapterName, nil
+	}
+
+	tag, _ := strings.CutPrefix(c.Grouping, GroupingByTagPrefix)
+	tags, _, err := track.Metadata()
+	if err != nil {
+		return "", err
+	}
+	return tags[strings.ToLower(tag)], nil
+}
@@ -0,0 +1,185 @@
+package m4b_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// requireGolden compares actual with the golden file testdata/<name>.golden.
+// With -update, the golden file is written instead.
+func requireGolden(t *testing.T, name string, actual string) {
+	t.Helper()
+
+	golden := filepath.Join("testdata", name+".golden")
+	if *update {
+		require.NoError(t, os.MkdirAll(filepath.Dir(golden), 0755))
+		require.NoError(t, os.WriteFile(golden, []byte(actual), 0644))
+	}
+
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), actual)
+}
+
+var chapterFiles = []string{
+	"/book/CD1/01.flac", "/book/CD1/02.flac", "/book/CD1/03.flac",
+	"/book/CD2/01.flac", "/book/CD2/02.flac", "/book/CD2/03.flac",
+}
+
+var chapterData = map[string]m4b.FileData{
+	"/book/CD1/01.flac": {Title: "Intro", Duration: 60, Metadata: ";FFMETADATA1\ntitle=Intro\ndisc=1\ntrack=1\nwork=Prolog"},
+	"/book/CD1/02.flac": {Title: "Intermezzo", Duration: 61.5, Metadata: ";FFMETADATA1\ntitle=Intermezzo\ndisc=1\ntrack=2\nwork=Prolog"},
+	"/book/CD1/03.flac": {Title: "Kapitel 1", Duration: 62, Metadata: ";FFMETADATA1\ntitle=Kapitel 1\ndisc=1\ntrack=3\nwork=Teil 1"},
+	"/book/CD2/01.flac": {Title: "Kapitel 1", Duration: 63, Metadata: ";FFMETADATA1\ntitle=Kapitel 1\ndisc=2\ntrack=1\nwork=Teil 1"},
+	"/book/CD2/02.flac": {Title: "Intermezzo", Duration: 64, Metadata: ";FFMETADATA1\ntitle=Intermezzo\ndisc=2\ntrack=2\nwork=Teil 1"},
+	"/book/CD2/03.flac": {Title: "Intermezzo", Duration: 65, Metadata: ";FFMETADATA1\ntitle=Intermezzo\ndisc=2\ntrack=3\nwork=Teil 2"},
+}
+
+func TestChapters_Grouping(t *testing.T) {
+	tests := []struct {
+		name     string
+		grouping string
+	}{
+		{name: "default", grouping: ""},
+		{name: "contiguous", grouping: "contiguous"},
+		{name: "per-file", grouping: "per-file"},
+		{name: "per-disc", grouping: "per-disc"},
+		{name: "per-directory", grouping: "per-directory"},
+		{name: "by-tag", grouping: "by-tag:work"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := m4b.ProjectConfig{
+				ProjectPath: "/book",
+				HasChapters: true,
+				Chapters:    m4b.ChaptersConfig{Grouping: tt.grouping},
+			}
+			project, err := m4b.NewProjectWithDeps(config, fakeDeps(chapterData, chapterFiles))
+			require.NoError(t, err)
+
+			chapters, err := project.Chapters()
+			require.NoError(t, err)
+			requireGolden(t, "chapters/grouping-"+tt.name, chapters)
+		})
+	}
+}
+
+func TestChapters_InvalidGrouping(t *testing.T) {
+	tests := []struct {
+		grouping string
+		err      string
+	}{
+		{"per-track", "unknown grouping 'per-track'"},
+		{"by-tag:", "grouping by-tag: requires a tag name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.grouping, func(t *testing.T) {
+			config := m4b.ProjectConfig{Chapters: m4b.ChaptersConfig{Grouping: tt.grouping}}
+			require.ErrorContains(t, config.Validate(), tt.err)
+		})
+	}
+}
+
+func TestChapters_Title(t *testing.T) {
+	tests := []struct {
+		name     string
+		chapters m4b.ChaptersConfig
+		rules    []m4b.MetadataRule
+	}{
+		{
+			name:     "tag",
+			chapters: m4b.ChaptersConfig{Title: "tag"},
+			rules:    []m4b.MetadataRule{{Type: "case", Tag: "title", Case: "upper"}},
+		},
+		{name: "tag-work", chapters: m4b.ChaptersConfig{Title: "tag:work"}},
+		{name: "filename", chapters: m4b.ChaptersConfig{Title: "filename", Grouping: "per-file"}},
+		{name: "directory", chapters: m4b.ChaptersConfig{Title: "directory"}},
+		{
+			name: "template",
+			chapters: m4b.ChaptersConfig{
+				Title:    "template",
+				Template: `Kapitel {{printf "%02d" .Index}} (CD {{.Disc}}, {{.Track}}): {{.work}}`,
+				Grouping: "by-tag:work",
+			},
+		},
+		{
+			name:     "template-contiguous",
+			chapters: m4b.ChaptersConfig{Title: "template", Template: "{{.Index}}. {{.title}}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := m4b.ProjectConfig{
+				ProjectPath:   "/book",
+				HasChapters:   true,
+				MetadataRules: tt.rules,
+				Chapters:      tt.chapters,
+			}
+			project, err := m4b.NewProjectWithDeps(config, fakeDeps(chapterData, chapterFiles))
+			require.NoError(t, err)
+
+			chapters, err := project.Chapters()
+			require.NoError(t, err)
+			requireGolden(t, "chapters/title-"+tt.name, chapters)
+		})
+	}
+}
+
+func TestChapters_TitleWithChapterRules(t *testing.T) {
+	config := m4b.ProjectConfig{
+		ProjectPath:  "/book",
+		HasChapters:  true,
+		Chapters:     m4b.ChaptersConfig{Title: "filename", Grouping: "per-file"},
+		ChapterRules: []m4b.ChapterRule{{Regex: `^(\d+)$`, Format: "Kapitel %03d"}},
+	}
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(chapterData, chapterFiles[:2]))
+	require.NoError(t, err)
+
+	chapters, err := project.Chapters()
+	require.NoError(t, err)
+	require.Contains(t, chapters, "CHAPTER0NAME=Kapitel 001")
+	require.Contains(t, chapters, "CHAPTER1NAME=Kapitel 002")
+}
+
+func TestChapters_InvalidTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		chapters m4b.ChaptersConfig
+		err      string
+	}{
+		{"unknown source", m4b.ChaptersConfig{Title: "comment"}, "unknown title 'comment'"},
+		{"empty tag", m4b.ChaptersConfig{Title: "tag:"}, "title tag: requires a tag name"},
+		{"missing template", m4b.ChaptersConfig{Title: "template"}, "title template requires a template"},
+		{"template without source", m4b.ChaptersConfig{Template: "{{.Index}}"}, "template is only allowed with title template"},
+		{"invalid template", m4b.ChaptersConfig{Title: "template", Template: "{{.Index"}, "template '{{.Index' is invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := m4b.ProjectConfig{Chapters: tt.chapters}
+			require.ErrorContains(t, config.Validate(), tt.err)
+		})
+	}
+}
+
+func TestChapters_TemplateMissingTag(t *testing.T) {
+	config := m4b.ProjectConfig{
+		ProjectPath: "/book",
+		Chapters:    m4b.ChaptersConfig{Title: "template", Template: "{{.narrator}}"},
+	}
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(chapterData, chapterFiles))
+	require.NoError(t, err)
+
+	_, err = project.Chapters()
+	require.ErrorContains(t, err, "could not render chapter title of /book/CD1/01.flac")
+}
@@ -0,0 +1,263 @@
+package m4b
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Condition restricts a rule to the tracks it holds for. All predicates that
+// are set have to hold. Predicates can be combined with all, any and not.
+//
+// Number predicates (track, disc) and duration take an optional comparison
+// operator (=, !=, <, <=, >, >=) or a range, e.g. "3", ">=3", "2-5", ">10m".
+// Durations use Go syntax (90s, 10m, 1h30m) or plain seconds.
+type Condition struct {
+	// Tag selects the tag for matches, equals and exists. Without any of those,
+	// the tag has to exist and be non-empty.
+	Tag     string `yaml:"tag,omitempty"`
+	Matches string `yaml:"matches,omitempty"`
+	Equals  string `yaml:"equals,omitempty"`
+	Exists  *bool  `yaml:"exists,omitempty"`
+	// Path is a regex matched against the file path relative to the project,
+	// using / as separator, e.g. "^CD2/".
+	Path     string      `yaml:"path,omitempty"`
+	Track    string      `yaml:"track,omitempty"`
+	Disc     string      `yaml:"disc,omitempty"`
+	Duration string      `yaml:"duration,omitempty"`
+	All      []Condition `yaml:"all,omitempty"`
+	Any      []Condition `yaml:"any,omitempty"`
+	Not      *Condition  `yaml:"not,omitempty"`
+}
+
+// RuleContext is the data a Condition is evaluated against.
+type RuleContext struct {
+	Tags     map[string]string
+	Path     string  // path relative to the project, separated by /
+	Duration float64 // duration in seconds
+}
+
+// Validate checks that all regexes and comparisons of the condition can be parsed.
+func (c *Condition) Validate() error {
+	if (c.Matches != "" || c.Equals != "" || c.Exists != nil) && c.Tag == "" {
+		return errors.New("condition with matches, equals or exists requires a tag")
+	}
+
+	for _, regex := range []string{c.Matches, c.Path} {
+		if regex == "" {
+			continue
+		}
+		if _, err := regexp.Compile(regex); err != nil {
+			return fmt.Errorf("condition regex '%s' is invalid: %w", regex, err)
+		}
+	}
+
+	if _, err := compareNumber(c.Track, 0, parseFloat); err != nil {
+		return fmt.Errorf("condition track invalid: %w", err)
+	}
+	if _, err := compareNumber(c.Disc, 0, parseFloat); err != nil {
+		return fmt.Errorf("condition disc invalid: %w", err)
+	}
+	if _, err := compareNumber(c.Duration, 0, parseSeconds); err != nil {
+		return fmt.Errorf("condition duration invalid: %w", err)
+	}
+
+	for _, condition := range slices.Concat(c.All, c.Any) {
+		if err := condition.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Not != nil {
+		return c.Not.Validate()
+	}
+
+	return nil
+}
+
+// Evaluate returns whether the condition holds for the given context.
+func (c *Condition) Evaluate(ctx RuleContext) (bool, error) {
+	if c.Tag != "" {
+		value, exists := ctx.Tags[strings.ToLower(c.Tag)]
+
+		switch {
+		case c.Exists != nil:
+			if exists != *c.Exists {
+				return false, nil
+			}
+		case c.Matches == "" && c.Equals == "":
+			if value == "" {
+				return false, nil
+			}
+		}
+
+		if c.Equals != "" && value != c.Equals {
+			return false, nil
+		}
+
+		if c.Matches != "" {
+			regex, err := regexp.Compile(c.Matches)
+			if err != nil {
+				return false, fmt.Errorf("condition regex '%s' is invalid: %w", c.Matches, err)
+			}
+			if !exists || !regex.MatchString(value) {
+				return false, nil
+			}
+		}
+	}
+
+	if c.Path != "" {
+		regex, err := regexp.Compile(c.Path)
+		if err != nil {
+			return false, fmt.Errorf("condition regex '%s' is invalid: %w", c.Path, err)
+		}
+		if !regex.MatchString(ctx.Path) {
+			return false, nil
+		}
+	}
+
+	numbers := []struct {
+		expr string
+		tag  string
+	}{
+		{c.Track, "track"},
+		{c.Disc, "disc"},
+	}
+	for _, number := range numbers {
+		if number.expr == "" {
+			continue
+		}
+		value, ok := parseNumberTag(ctx.Tags[number.tag])
+		if !ok {
+			return false, nil
+		}
+		if holds, err := compareNumber(number.expr, float64(value), parseFloat); err != nil || !holds {
+			return false, err
+		}
+	}
+
+	if holds, err := compareNumber(c.Duration, ctx.Duration, parseSeconds); err != nil || !holds {
+		return false, err
+	}
+
+	for _, condition := range c.All {
+		if holds, err := condition.Evaluate(ctx); err != nil || !holds {
+			return false, err
+		}
+	}
+
+	if len(c.Any) > 0 {
+		anyHolds := false
+		for _, condition := range c.Any {
+			holds, err := condition.Evaluate(ctx)
+			if err != nil {
+				return false, err
+			}
+			if holds {
+				anyHolds = true
+				break
+			}
+		}
+		if !anyHolds {
+			return false, nil
+		}
+	}
+
+	if c.Not != nil {
+		holds, err := c.Not.Evaluate(ctx)
+		if err != nil || holds {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// compareNumber evaluates expressions like "3", ">=3", "!=2" or "2-5" against
+// value. An empty expression always holds.
+func compareNumber(expr string, value float64, parse func(string) (float64, error)) (bool, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return true, nil
+	}
+
+	for _, op := range []string{">=", "<=", "!=", "==", ">", "<", "="} {
+		if !strings.HasPrefix(expr, op) {
+			continue
+		}
+
+		operand, err := parse(strings.TrimSpace(strings.TrimPrefix(expr, op)))
+		if err != nil {
+			return false, fmt.Errorf("invalid comparison '%s': %w", expr, err)
+		}
+
+		switch op {
+		case ">=":
+			return value >= operand, nil
+		case "<=":
+			return value <= operand, nil
+		case "!=":
+			return value != operand, nil
+		case ">":
+			return value > operand, nil
+		case "<":
+			return value < operand, nil
+		default:
+			return value == operand, nil
+		}
+	}
+
+	if from, to, isRange := strings.Cut(expr, "-"); isRange {
+		lower, err := parse(strings.TrimSpace(from))
+		if err != nil {
+			return false, fmt.Errorf("invalid range '%s': %w", expr, err)
+		}
+		upper, err := parse(strings.TrimSpace(to))
+		if err != nil {
+			return false, fmt.Errorf("invalid range '%s': %w", expr, err)
+		}
+		return value >= lower && value <= upper, nil
+	}
+
+	operand, err := parse(expr)
+	if err != nil {
+		return false, fmt.Errorf("invalid comparison '%s': %w", expr, err)
+	}
+	return value == operand, nil
+}
+
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseSeconds parses a Go duration (e.g. 1h30m) or a plain number of seconds.
+func parseSeconds(s string) (float64, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return seconds, nil
+	}
+
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	return duration.Seconds(), nil
+}
+
+// parseNumberTag parses tag values in the format "n" or "n/total".
+func parseNumberTag(value string) (int, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	parts := strings.Split(value, "/")
+
+	number, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
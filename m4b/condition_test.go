@@ -0,0 +1,131 @@
+package m4b_test
+
+import (
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCondition_Evaluate(t *testing.T) {
+	no := false
+	ctx := m4b.RuleContext{
+		Tags: map[string]string{
+			"album": "Die drei ??? Folge 12",
+			"track": "3/12",
+			"disc":  "2/2",
+		},
+		Path:     "CD2/03 Track.flac",
+		Duration: 600,
+	}
+
+	tests := []struct {
+		name      string
+		condition m4b.Condition
+		expected  bool
+	}{
+		{name: "empty", condition: m4b.Condition{}, expected: true},
+		{name: "tag matches", condition: m4b.Condition{Tag: "Album", Matches: `^Die drei \?\?\?`}, expected: true},
+		{name: "tag does not match", condition: m4b.Condition{Tag: "album", Matches: `^TKKG`}, expected: false},
+		{name: "tag equals", condition: m4b.Condition{Tag: "disc", Equals: "2/2"}, expected: true},
+		{name: "tag present", condition: m4b.Condition{Tag: "album"}, expected: true},
+		{name: "tag missing", condition: m4b.Condition{Tag: "genre"}, expected: false},
+		{name: "tag must not exist", condition: m4b.Condition{Tag: "genre", Exists: &no}, expected: true},
+		{name: "path", condition: m4b.Condition{Path: "^CD2/"}, expected: true},
+		{name: "other path", condition: m4b.Condition{Path: "^CD1/"}, expected: false},
+		{name: "track equals", condition: m4b.Condition{Track: "3"}, expected: true},
+		{name: "track greater", condition: m4b.Condition{Track: ">3"}, expected: false},
+		{name: "track range", condition: m4b.Condition{Track: "2-5"}, expected: true},
+		{name: "disc", condition: m4b.Condition{Disc: ">=2"}, expected: true},
+		{name: "duration", condition: m4b.Condition{Duration: ">5m"}, expected: true},
+		{name: "duration seconds", condition: m4b.Condition{Duration: "<300"}, expected: false},
+		{name: "combined predicates", condition: m4b.Condition{Path: "^CD2/", Track: "1"}, expected: false},
+		{
+			name: "all",
+			condition: m4b.Condition{All: []m4b.Condition{
+				{Tag: "album", Matches: "Folge"},
+				{Disc: "2"},
+			}},
+			expected: true,
+		},
+		{
+			name: "any",
+			condition: m4b.Condition{Any: []m4b.Condition{
+				{Path: "^CD1/"},
+				{Track: "3"},
+			}},
+			expected: true,
+		},
+		{
+			name:      "not",
+			condition: m4b.Condition{Not: &m4b.Condition{Path: "^CD2/"}},
+			expected:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.condition.Validate())
+			holds, err := tt.condition.Evaluate(ctx)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, holds)
+		})
+	}
+}
+
+func TestCondition_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition m4b.Condition
+		errMsg    string
+	}{
+		{name: "matches without tag", condition: m4b.Condition{Matches: "x"}, errMsg: "requires a tag"},
+		{name: "invalid regex", condition: m4b.Condition{Path: "("}, errMsg: "condition regex '(' is invalid"},
+		{name: "invalid track", condition: m4b.Condition{Track: ">x"}, errMsg: "condition track invalid"},
+		{name: "invalid duration", condition: m4b.Condition{Duration: "10 minutes"}, errMsg: "condition duration invalid"},
+		{name: "nested", condition: m4b.Condition{Not: &m4b.Condition{Any: []m4b.Condition{{Disc: "a-b"}}}}, errMsg: "condition disc invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.condition.Validate(), tt.errMsg)
+		})
+	}
+}
+
+func TestConditionalRules(t *testing.T) {
+	data := map[string]m4b.FileData{
+		"CD1/01.flac": {Title: "Intro", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Book\ndisc=1\ntrack=1"},
+		"CD2/01.flac": {Title: "Outro", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Book\ndisc=2\ntrack=1"},
+	}
+
+	config := m4b.ProjectConfig{
+		MetadataRules: []m4b.MetadataRule{
+			{Type: "set", Tag: "comment", Value: "second disc", When: &m4b.Condition{Path: "^CD2/"}},
+		},
+		ChapterRules: []m4b.ChapterRule{
+			{Regex: "(.*)", Format: "Bonus: %s", When: &m4b.Condition{Disc: "2"}},
+		},
+	}
+
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, []string{"CD2/01.flac", "CD1/01.flac"}))
+	require.NoError(t, err)
+
+	tracks, err := project.Tracks()
+	require.NoError(t, err)
+
+	_, exists := tracks[0].MetadataTag("comment")
+	require.False(t, exists)
+	comment, _ := tracks[1].MetadataTag("comment")
+	require.Equal(t, "second disc", comment)
+
+	trace, err := tracks[0].MetadataTrace()
+	require.NoError(t, err)
+	require.Len(t, trace, 1)
+	require.True(t, trace[0].Skipped)
+
+	chapters, err := project.Chapters()
+	require.NoError(t, err)
+	require.Contains(t, chapters, "CHAPTER0NAME=Intro")
+	require.Contains(t, chapters, "CHAPTER1NAME=Bonus: Outro")
+}
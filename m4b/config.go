@@ -11,19 +11,56 @@ import (
 // ProjectConfig represents the configuration for an M4B audiobook project,
 // including paths to required files and rules for metadata and chapters.
 type ProjectConfig struct {
-	CoverPath     string         `yaml:"coverPath"`
-	HasChapters   bool           `yaml:"hasChapters"`
-	MetadataRules []MetadataRule `yaml:"metadataRules"`
-	ChapterRules  []ChapterRule  `yaml:"chapterRules"`
-	ShouldConvert bool           `yaml:"shouldConvert"`
-	Multi         bool           `yaml:"multi"`
-	ProjectPath   string         `yaml:"projectPath,omitempty"`
-	outputPath    string         `yaml:"outputPath,omitempty"`
+	Presets     []string `yaml:"presets,omitempty"`
+	CoverPath   string   `yaml:"coverPath"`
+	HasChapters bool     `yaml:"hasChapters"`
+	// Input selects the audio files: extensions, include/exclude globs and max depth.
+	Input     InputConfig `yaml:"input,omitempty"`
+	PathRules []PathRule  `yaml:"pathRules,omitempty"`
+	// DiscDetection infers disc numbers from directories like CD1/ when the
+	// disc tag is missing.
+	DiscDetection DiscDetectionConfig `yaml:"discDetection,omitempty"`
+	MetadataRules []MetadataRule      `yaml:"metadataRules"`
+	// Aggregation maps tags to the strategy that combines the values of all
+	// tracks into the book metadata: first (default, the value of the first
+	// track), most-common, union or require-consistent. The key "*" sets the
+	// strategy for all other tags.
+	Aggregation  map[string]string `yaml:"aggregation,omitempty"`
+	ChapterRules []ChapterRule     `yaml:"chapterRules"`
+	// Chapters configures how the tracks are grouped into chapters.
+	Chapters ChaptersConfig `yaml:"chapters,omitempty"`
+	Ordering OrderingConfig `yaml:"ordering,omitempty"`
+	// GroupBy splits the tracks of one folder into several projects:
+	// album, album+disc or directory. Empty keeps all tracks in one project.
+	GroupBy       string        `yaml:"groupBy,omitempty"`
+	ShouldConvert bool          `yaml:"shouldConvert"`
+	Multi         bool          `yaml:"multi"`
+	Sidecars      SidecarConfig `yaml:"sidecars,omitempty"`
+	ProjectPath   string        `yaml:"projectPath,omitempty"`
+	outputPath    string        `yaml:"outputPath,omitempty"`
 }
 
 // Validate checks if the ProjectConfig is valid by ensuring required fields
 // are present and all rules are valid. Returns an error if validation fails.
 func (c *ProjectConfig) Validate() error {
+	if err := c.Input.Validate(); err != nil {
+		return fmt.Errorf("input invalid: %w", err)
+	}
+	if ext := c.Input.conversionRequired(); ext != "" && !c.ShouldConvert {
+		return fmt.Errorf("input invalid: %s files can not be concatenated as they are, set shouldConvert: true", ext)
+	}
+
+	for _, rule := range c.PathRules {
+		err := rule.Validate()
+		if err != nil {
+			return fmt.Errorf("path rule invalid: %w", err)
+		}
+	}
+
+	if err := c.DiscDetection.Validate(); err != nil {
+		return fmt.Errorf("disc detection invalid: %w", err)
+	}
+
 	for _, rule := range c.MetadataRules {
 		err := rule.Validate()
 		if err != nil {
@@ -38,6 +75,26 @@ func (c *ProjectConfig) Validate() error {
 		}
 	}
 
+	if err := c.Chapters.Validate(); err != nil {
+		return fmt.Errorf("chapters invalid: %w", err)
+	}
+
+	if err := validateAggregation(c.Aggregation); err != nil {
+		return fmt.Errorf("aggregation invalid: %w", err)
+	}
+
+	if err := c.Ordering.Validate(); err != nil {
+		return fmt.Errorf("ordering invalid: %w", err)
+	}
+
+	if err := validateGroupBy(c.GroupBy); err != nil {
+		return err
+	}
+
+	if err := c.Sidecars.Validate(); err != nil {
+		return fmt.Errorf("sidecars invalid: %w", err)
+	}
+
 	return nil
 }
 
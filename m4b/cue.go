@@ -0,0 +1,338 @@
+package m4b
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// cueFramesPerSecond is the number of frames per second in CUE timestamps (mm:ss:ff).
+const cueFramesPerSecond = 75
+
+// CueSheet is a parsed CUE sheet, describing the tracks of one or more
+// audio files.
+type CueSheet struct {
+	File      string // path of the cue sheet
+	Title     string
+	Performer string
+	Date      string
+	Genre     string
+	Disc      string
+	Files     []CueFile
+}
+
+// CueFile is an audio file referenced by a cue sheet.
+type CueFile struct {
+	Name   string // path resolved against the directory of the cue sheet
+	Tracks []CueTrack
+}
+
+// CueTrack is a track within a CueFile.
+type CueTrack struct {
+	Number    int
+	Title     string
+	Performer string
+	Start     float64 // INDEX 01 in seconds from the start of the file
+}
+
+// ReadCueSheet reads and parses the cue sheet at file.
+// Cue sheets that are not valid UTF-8 are read as Latin-1.
+func ReadCueSheet(file string) (*CueSheet, error) {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	content := string(bytes)
+	if !utf8.ValidString(content) {
+		runes := make([]rune, len(bytes))
+		for i, b := range bytes {
+			runes[i] = rune(b)
+		}
+		content = string(runes)
+	}
+
+	sheet, err := ParseCueSheet(content, filepath.Dir(file))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse cue sheet %s: %w", file, err)
+	}
+	sheet.File = file
+	return sheet, nil
+}
+
+// ParseCueSheet parses the content of a cue sheet. FILE entries are resolved
+// against dir. Only FILE, TRACK, INDEX 01, TITLE, PERFORMER and REM DATE,
+// GENRE and DISCNUMBER are evaluated.
+func ParseCueSheet(content string, dir string) (*CueSheet, error) {
+	sheet := &CueSheet{}
+
+	var file *CueFile
+	var track *CueTrack
+
+	content = strings.TrimPrefix(content, "\ufeff")
+	for i, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		fields := cueFields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		command := strings.ToUpper(fields[0])
+		args := fields[1:]
+		arg := func(n int) string {
+			if n < len(args) {
+				return args[n]
+			}
+			return ""
+		}
+
+		switch command {
+		case "REM":
+			switch strings.ToUpper(arg(0)) {
+			case "DATE":
+				sheet.Date = arg(1)
+			case "GENRE":
+				sheet.Genre = arg(1)
+			case "DISCNUMBER":
+				sheet.Disc = arg(1)
+			}
+		case "TITLE":
+			if track != nil {
+				track.Title = arg(0)
+			} else {
+				sheet.Title = arg(0)
+			}
+		case "PERFORMER":
+			if track != nil {
+				track.Performer = arg(0)
+			} else {
+				sheet.Performer = arg(0)
+			}
+		case "FILE":
+			name := filepath.FromSlash(strings.ReplaceAll(arg(0), `\`, "/"))
+			if name == "" {
+				return nil, fmt.Errorf("line %d: FILE without name", i+1)
+			}
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(dir, name)
+			}
+			sheet.Files = append(sheet.Files, CueFile{Name: name})
+			file = &sheet.Files[len(sheet.Files)-1]
+			track = nil
+		case "TRACK":
+			if file == nil {
+				return nil, fmt.Errorf("line %d: TRACK before FILE", i+1)
+			}
+			number, err := strconv.Atoi(arg(0))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid track number '%s'", i+1, arg(0))
+			}
+			file.Tracks = append(file.Tracks, CueTrack{Number: number, Start: -1})
+			track = &file.Tracks[len(file.Tracks)-1]
+		case "INDEX":
+			if track == nil {
+				return nil, fmt.Errorf("line %d: INDEX outside of TRACK", i+1)
+			}
+			if arg(0) != "01" && arg(0) != "1" {
+				continue
+			}
+			start, err := parseCueTime(arg(1))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			track.Start = start
+		}
+	}
+
+	for _, file := range sheet.Files {
+		for _, track := range file.Tracks {
+			if track.Start < 0 {
+				return nil, fmt.Errorf("track %d has no INDEX 01", track.Number)
+			}
+		}
+	}
+
+	return sheet, nil
+}
+
+// cueFields splits a line into its fields. Quoted fields may contain spaces.
+func cueFields(line string) []string {
+	var fields []string
+	line = strings.TrimSpace(line)
+
+	for line != "" {
+		var field string
+		if line[0] == '"' {
+			end := strings.IndexByte(line[1:], '"')
+			if end < 0 {
+				field, line = line[1:], ""
+			} else {
+				field, line = line[1:end+1], line[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				field, line = line, ""
+			} else {
+				field, line = line[:end], line[end:]
+			}
+		}
+		fields = append(fields, field)
+		line = strings.TrimSpace(line)
+	}
+
+	return fields
+}
+
+// parseCueTime parses a timestamp in the format mm:ss:ff, with 75 frames per second.
+func parseCueTime(value string) (float64, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid cue time '%s'", value)
+	}
+
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid cue time '%s'", value)
+		}
+		numbers[i] = n
+	}
+
+	return float64(numbers[0]*60+numbers[1]) + float64(numbers[2])/cueFramesPerSecond, nil
+}
+
+// expandCueSheets replaces tracks that are referenced by a cue sheet in their
+// directory by one virtual track per cue track. Virtual tracks keep the tags
+// of the file, overridden by the title, performer and track number of the cue
+// track and the title, date and genre of the sheet.
+func expandCueSheets(tracks []Track) ([]Track, error) {
+	var dirs []string
+	for _, track := range tracks {
+		if dir := filepath.Dir(track.File); !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	var sheets []*CueSheet
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			// the tracks do not have to exist on disk, e.g. in tests
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".cue") {
+				continue
+			}
+			sheet, err := ReadCueSheet(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			sheets = append(sheets, sheet)
+		}
+	}
+
+	if len(sheets) == 0 {
+		return tracks, nil
+	}
+
+	audioFilesPerDir := map[string]int{}
+	for _, track := range tracks {
+		audioFilesPerDir[filepath.Dir(track.File)]++
+	}
+
+	expanded := make([]Track, 0, len(tracks))
+	for _, track := range tracks {
+		onlyAudioFile := audioFilesPerDir[filepath.Dir(track.File)] == 1
+		sheet, file, found := findCueFile(sheets, track.File, onlyAudioFile)
+		if !found || len(file.Tracks) == 0 {
+			expanded = append(expanded, track)
+			continue
+		}
+
+		for i, cueTrack := range file.Tracks {
+			end := track.duration
+			if i+1 < len(file.Tracks) {
+				end = file.Tracks[i+1].Start
+			}
+			if end <= cueTrack.Start {
+				return nil, fmt.Errorf("cue sheet %s: track %d ends before it starts", sheet.File, cueTrack.Number)
+			}
+
+			virtual := track
+			virtual.title = cueTrack.Title
+			virtual.start = cueTrack.Start
+			virtual.end = end
+			virtual.duration = end - cueTrack.Start
+			virtual.cueSheet = sheet.File
+			virtual.rawMetadata = cueTrackMetadata(track, sheet, file, cueTrack)
+			virtual.metadataCache = nil
+			virtual.tagOrder = nil
+			expanded = append(expanded, virtual)
+		}
+	}
+
+	return expanded, nil
+}
+
+// findCueFile returns the cue file referencing audioFile. Cue sheets often
+// reference the original rip (e.g. .wav) of a converted file, so if
+// audioFile is the only audio file in its directory, the filename without
+// extension is compared if there is no exact match.
+func findCueFile(sheets []*CueSheet, audioFile string, onlyAudioFile bool) (*CueSheet, CueFile, bool) {
+	stem := func(file string) string {
+		return strings.ToLower(strings.TrimSuffix(file, filepath.Ext(file)))
+	}
+
+	modes := []bool{true}
+	if onlyAudioFile {
+		modes = append(modes, false)
+	}
+
+	for _, exact := range modes {
+		for _, sheet := range sheets {
+			for _, file := range sheet.Files {
+				if exact && filepath.Clean(file.Name) == filepath.Clean(audioFile) ||
+					!exact && stem(file.Name) == stem(audioFile) {
+					return sheet, file, true
+				}
+			}
+		}
+	}
+
+	return nil, CueFile{}, false
+}
+
+func cueTrackMetadata(track Track, sheet *CueSheet, file CueFile, cueTrack CueTrack) string {
+	tags, tagOrder := track.getMetadataTags(track.rawMetadata)
+
+	set := func(tag string, value string) {
+		if value == "" {
+			return
+		}
+		if _, exists := tags[tag]; !exists {
+			tagOrder = append(tagOrder, tag)
+		}
+		tags[tag] = value
+	}
+
+	performer := cueTrack.Performer
+	if performer == "" {
+		performer = sheet.Performer
+	}
+
+	set("album", sheet.Title)
+	set("artist", performer)
+	set("title", cueTrack.Title)
+	set("track", fmt.Sprintf("%d/%d", cueTrack.Number, len(file.Tracks)))
+	set("disc", sheet.Disc)
+	set("date", sheet.Date)
+	set("genre", sheet.Genre)
+
+	return formatMetadata(tags, tagOrder)
+}
@@ -0,0 +1,145 @@
+package m4b_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+const testCue = `REM GENRE Hörspiel
+REM DATE 1981
+PERFORMER "Die drei ???"
+TITLE "Der Superpapagei"
+FILE "Der Superpapagei.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "Ein Papagei"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Die Spur"
+    PERFORMER "Justus Jonas"
+    INDEX 00 05:09:70
+    INDEX 01 05:10:37
+  TRACK 03 AUDIO
+    TITLE "Das Ende"
+    INDEX 01 10:00:00
+`
+
+func TestParseCueSheet(t *testing.T) {
+	sheet, err := m4b.ParseCueSheet(testCue, "/rip")
+	require.NoError(t, err)
+
+	require.Equal(t, "Der Superpapagei", sheet.Title)
+	require.Equal(t, "Die drei ???", sheet.Performer)
+	require.Equal(t, "1981", sheet.Date)
+	require.Equal(t, "Hörspiel", sheet.Genre)
+	require.Len(t, sheet.Files, 1)
+	require.Equal(t, "/rip/Der Superpapagei.wav", sheet.Files[0].Name)
+	require.Equal(t, []m4b.CueTrack{
+		{Number: 1, Title: "Ein Papagei", Start: 0},
+		{Number: 2, Title: "Die Spur", Performer: "Justus Jonas", Start: 310 + 37.0/75},
+		{Number: 3, Title: "Das Ende", Start: 600},
+	}, sheet.Files[0].Tracks)
+}
+
+func TestParseCueSheet_Errors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{"TRACK 01 AUDIO", "line 1: TRACK before FILE"},
+		{"FILE \"a.flac\" WAVE\nTRACK x AUDIO", "line 2: invalid track number 'x'"},
+		{"FILE \"a.flac\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00", "line 3: invalid cue time '00:00'"},
+		{"FILE \"a.flac\" WAVE\nTRACK 01 AUDIO\nINDEX 00 00:00:00", "track 1 has no INDEX 01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			_, err := m4b.ParseCueSheet(tt.content, "/")
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestCueSheet_Project(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rip.cue"), []byte(testCue), 0644))
+
+	// the cue sheet references the .wav of the original rip
+	flac := filepath.Join(dir, "Der Superpapagei.flac")
+	data := map[string]m4b.FileData{
+		flac: {Title: "Der Superpapagei", Duration: 900, Metadata: ";FFMETADATA1\nalbum=Superpapagei\nencoder=flac"},
+	}
+
+	config := m4b.ProjectConfig{
+		ProjectPath:  dir,
+		HasChapters:  true,
+		ChapterRules: []m4b.ChapterRule{{Regex: "^(?:Ein|Die|Das) (.+)$", Format: "%s"}},
+	}
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, []string{flac}))
+	require.NoError(t, err)
+
+	tracks, err := project.Tracks()
+	require.NoError(t, err)
+	require.Len(t, tracks, 3)
+
+	start, end, isSegment := tracks[1].Segment()
+	require.True(t, isSegment)
+	require.InDelta(t, 310.493, start, 0.001)
+	require.Equal(t, 600.0, end)
+
+	tags, _, err := tracks[1].Metadata()
+	require.NoError(t, err)
+	require.Equal(t, "Der Superpapagei", tags["album"])
+	require.Equal(t, "Justus Jonas", tags["artist"])
+	require.Equal(t, "Die Spur", tags["title"])
+	require.Equal(t, "2/3", tags["track"])
+	require.Equal(t, "flac", tags["encoder"])
+
+	chapters, err := project.Chapters()
+	require.NoError(t, err)
+	require.Equal(t, `CHAPTER0=00:00:00.000
+CHAPTER0NAME=Papagei
+
+CHAPTER1=00:05:10.493
+CHAPTER1NAME=Spur
+
+CHAPTER2=00:10:00.000
+CHAPTER2NAME=Ende`, chapters)
+
+	metadata, err := project.Metadata()
+	require.NoError(t, err)
+	require.Equal(t, `;FFMETADATA1
+album=Der Superpapagei
+encoder=flac
+artist=Die drei ???
+title=Ein Papagei
+date=1981
+genre=Hörspiel`, metadata)
+}
+
+func TestCueSheet_StemFallbackOnlyForSingleAudioFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rip.cue"), []byte(testCue), 0644))
+
+	// the flac shares the stem of the .wav in the cue sheet, but is one of several files
+	flac := filepath.Join(dir, "Der Superpapagei.flac")
+	bonus := filepath.Join(dir, "Bonus.flac")
+	data := map[string]m4b.FileData{
+		flac:  {Title: "Der Superpapagei", Duration: 900, Metadata: ";FFMETADATA1\nalbum=Superpapagei"},
+		bonus: {Title: "Bonus", Duration: 60, Metadata: ";FFMETADATA1\nalbum=Superpapagei"},
+	}
+
+	project, err := m4b.NewProjectWithDeps(m4b.ProjectConfig{ProjectPath: dir}, fakeDeps(data, []string{flac, bonus}))
+	require.NoError(t, err)
+
+	tracks, err := project.Tracks()
+	require.NoError(t, err)
+	require.Len(t, tracks, 2)
+	for _, track := range tracks {
+		_, _, isSegment := track.Segment()
+		require.False(t, isSegment)
+	}
+}
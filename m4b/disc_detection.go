@@ -0,0 +1,74 @@
+package m4b
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultDiscPatterns match directory names like "CD1", "CD 2", "Disc03" or "disk-4".
+var defaultDiscPatterns = []string{`(?i)^(?:cd|disc|disk)\s*[-_.]?\s*(\d+)$`}
+
+// DiscDetectionConfig configures how disc numbers are inferred from the
+// directories of tracks without a disc tag.
+type DiscDetectionConfig struct {
+	Disabled bool `yaml:"disabled,omitempty"`
+	// Patterns are regexes matched against the names of the directories
+	// containing a track, innermost first. The first capture group is the
+	// disc number. Defaults to CD1, CD 2, Disc03 and similar names.
+	Patterns []string `yaml:"patterns,omitempty"`
+}
+
+// Validate checks that all patterns compile and have a capture group.
+func (c *DiscDetectionConfig) Validate() error {
+	for _, pattern := range c.Patterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("disc pattern '%s' is invalid: %w", pattern, err)
+		}
+		if regex.NumSubexp() < 1 {
+			return fmt.Errorf("disc pattern '%s' needs a capture group for the disc number", pattern)
+		}
+	}
+	return nil
+}
+
+// Detect returns the disc number inferred from the directories of
+// relativePath, a path relative to the project separated by /, along with the
+// name of the matching directory.
+func (c *DiscDetectionConfig) Detect(relativePath string) (int, string, bool, error) {
+	if c.Disabled {
+		return 0, "", false, nil
+	}
+
+	patterns := c.Patterns
+	if len(patterns) == 0 {
+		patterns = defaultDiscPatterns
+	}
+
+	dirs := strings.Split(path.Dir(relativePath), "/")
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		for _, pattern := range patterns {
+			regex, err := regexp.Compile(pattern)
+			if err != nil {
+				return 0, "", false, fmt.Errorf("disc pattern '%s' is invalid: %w", pattern, err)
+			}
+
+			matches := regex.FindStringSubmatch(dirs[i])
+			if len(matches) < 2 {
+				continue
+			}
+
+			disc, err := strconv.Atoi(matches[1])
+			if err != nil {
+				continue
+			}
+			return disc, dirs[i], true, nil
+		}
+	}
+
+	return 0, "", false, nil
+}
@@ -0,0 +1,87 @@
+package m4b_test
+
+import (
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscDetection_Detect(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   m4b.DiscDetectionConfig
+		path     string
+		disc     int
+		dir      string
+		detected bool
+	}{
+		{name: "cd", path: "CD1/01.flac", disc: 1, dir: "CD1", detected: true},
+		{name: "cd with space", path: "CD 2/01.flac", disc: 2, dir: "CD 2", detected: true},
+		{name: "disc with leading zero", path: "Book/Disc03/01.flac", disc: 3, dir: "Disc03", detected: true},
+		{name: "innermost first", path: "Disk 1/CD-10/01.flac", disc: 10, dir: "CD-10", detected: true},
+		{name: "no disc dir", path: "Book/01.flac"},
+		{name: "file in project root", path: "01.flac"},
+		{name: "disabled", config: m4b.DiscDetectionConfig{Disabled: true}, path: "CD1/01.flac"},
+		{
+			name:     "custom pattern",
+			config:   m4b.DiscDetectionConfig{Patterns: []string{`^Teil (\d+)$`}},
+			path:     "Teil 4/01.flac",
+			disc:     4,
+			dir:      "Teil 4",
+			detected: true,
+		},
+		{name: "custom pattern replaces defaults", config: m4b.DiscDetectionConfig{Patterns: []string{`^Teil (\d+)$`}}, path: "CD1/01.flac"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disc, dir, detected, err := tt.config.Detect(tt.path)
+			require.NoError(t, err)
+			require.Equal(t, tt.detected, detected)
+			require.Equal(t, tt.disc, disc)
+			require.Equal(t, tt.dir, dir)
+		})
+	}
+}
+
+func TestDiscDetection_Validate(t *testing.T) {
+	config := m4b.ProjectConfig{DiscDetection: m4b.DiscDetectionConfig{Patterns: []string{`^CD\d+$`}}}
+	require.ErrorContains(t, config.Validate(), "needs a capture group")
+}
+
+func TestDiscDetection_Project(t *testing.T) {
+	files := []string{"/test/CD10/1.flac", "/test/CD2/1.flac", "/test/CD2/2.flac", "/test/CD3/1.flac"}
+	data := map[string]m4b.FileData{
+		"/test/CD10/1.flac": {Title: "Finale", Duration: 10, Metadata: ";FFMETADATA1\ntrack=1"},
+		"/test/CD2/1.flac":  {Title: "Anfang", Duration: 10, Metadata: ";FFMETADATA1\ntrack=1"},
+		"/test/CD2/2.flac":  {Title: "Mitte", Duration: 10, Metadata: ";FFMETADATA1\ntrack=2"},
+		"/test/CD3/1.flac":  {Title: "Ende", Duration: 10, Metadata: ";FFMETADATA1\ntrack=1\ndisc=5"},
+	}
+
+	config := m4b.ProjectConfig{
+		ProjectPath: "/test",
+		HasChapters: true,
+		ChapterRules: []m4b.ChapterRule{
+			{Regex: "(.+)", Format: "Bonus: %s", When: &m4b.Condition{Disc: ">=10"}},
+		},
+	}
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, files))
+	require.NoError(t, err)
+
+	tracks, err := project.Tracks()
+	require.NoError(t, err)
+
+	var sorted []string
+	for _, track := range tracks {
+		sorted = append(sorted, track.File)
+	}
+	// the disc tag of CD3/1.flac wins over its directory
+	require.Equal(t, []string{"/test/CD2/1.flac", "/test/CD2/2.flac", "/test/CD3/1.flac", "/test/CD10/1.flac"}, sorted)
+	require.NoError(t, project.ValidateOrder())
+
+	chapters, err := project.Chapters()
+	require.NoError(t, err)
+	require.Contains(t, chapters, "CHAPTER3NAME=Bonus: Finale")
+	require.Contains(t, chapters, "CHAPTER0NAME=Anfang")
+}
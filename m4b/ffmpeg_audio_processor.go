@@ -2,6 +2,7 @@ package m4b
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -80,6 +81,85 @@ func (p *FFmpegAudioProcessor) convertToM4AWorker(
 	}
 }
 
+// Cut copies the segment between start and end (in seconds) of file into
+// outputFile without re-encoding. The output format follows its extension.
+func (p *FFmpegAudioProcessor) Cut(file string, start float64, end float64, outputFile string) error {
+	cmd := p.Command.Create(
+		"ffmpeg",
+		"-i",
+		file,
+		"-ss",
+		strconv.FormatFloat(start, 'f', 3, 64),
+		"-to",
+		strconv.FormatFloat(end, 'f', 3, 64),
+		"-map_metadata",
+		"-1",
+		"-c",
+		"copy",
+		"-vn",
+		outputFile,
+	)
+
+	var outBuf bytes.Buffer
+	if err := cmd.Run(&outBuf, &outBuf); err != nil {
+		fmt.Println(outBuf.String())
+		return fmt.Errorf("could not cut %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// DetectSilence finds the silent parts of file using ffmpeg's silencedetect.
+// noise is the threshold, e.g. -30dB, minLength the minimum duration of a
+// silence in seconds. A silence running until the end of the file is ignored.
+func (p *FFmpegAudioProcessor) DetectSilence(file string, noise string, minLength float64) ([]Silence, error) {
+	cmd := p.Command.Create(
+		"ffmpeg",
+		"-hide_banner",
+		"-nostats",
+		"-i",
+		file,
+		"-af",
+		fmt.Sprintf("silencedetect=noise=%s:d=%s", noise, strconv.FormatFloat(minLength, 'f', -1, 64)),
+		"-f",
+		"null",
+		"-",
+	)
+
+	var stdout, stderr bytes.Buffer
+	if err := cmd.Run(&stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("could not detect silence in %s: %w\n%s", file, err, stderr.String())
+	}
+
+	startRegex := regexp.MustCompile(`silence_start: (-?[0-9.]+)`)
+	endRegex := regexp.MustCompile(`silence_end: (-?[0-9.]+)`)
+
+	var silences []Silence
+	start := -1.0
+
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		if match := startRegex.FindStringSubmatch(line); match != nil {
+			value, err := strconv.ParseFloat(match[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid silence_start '%s' in %s", match[1], file)
+			}
+			start = max(value, 0)
+			continue
+		}
+
+		if match := endRegex.FindStringSubmatch(line); match != nil && start >= 0 {
+			end, err := strconv.ParseFloat(match[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid silence_end '%s' in %s", match[1], file)
+			}
+			silences = append(silences, Silence{Start: start, End: end})
+			start = -1
+		}
+	}
+
+	return silences, nil
+}
+
 // Concat concatenates multiple audio files into a single M4B file
 // It takes input files, a temporary filelist path, and an output directory
 // Returns the path to the concatenated file or an error
@@ -207,6 +287,9 @@ func (p *FFmpegAudioProcessor) AddMetadata(m4bFile string, metadata string, book
 		"1",
 		"-c",
 		"copy",
+		// keep tags without an mp4 atom, e.g. publisher or isbn
+		"-movflags",
+		"use_metadata_tags",
 		"-metadata",
 		"title="+bookTitle,
 		tempFile,
@@ -258,7 +341,7 @@ func (p *FFmpegAudioProcessor) ChangeFileExtension(file string, ext string) stri
 }
 
 // ReadTitleAndDuration extracts the title and duration from a media file
-// Returns the title string and duration in seconds
+// Returns the title string (empty if the file has none) and duration in seconds
 func (p *FFmpegAudioProcessor) ReadTitleAndDuration(file string) (string, float64, error) {
 	dataCmd := p.Command.Create(
 		"ffprobe",
@@ -282,13 +365,12 @@ func (p *FFmpegAudioProcessor) ReadTitleAndDuration(file string) (string, float6
 	durationRegex := regexp.MustCompile(`duration=([0-9]+\.?[0-9]*)`)
 	titleRegex := regexp.MustCompile(`(?i)TAG:title=(.+)`)
 
-	titleMatch := titleRegex.FindStringSubmatch(probeContent)
-	if len(titleMatch) < 2 {
-		return "", 0, fmt.Errorf("title not found")
+	// untagged files have no title, which is fine as long as the duration is known
+	title := ""
+	if titleMatch := titleRegex.FindStringSubmatch(probeContent); len(titleMatch) >= 2 {
+		title = titleMatch[1]
 	}
 
-	title := titleMatch[1]
-
 	durationMatch := durationRegex.FindStringSubmatch(probeContent)
 	if len(durationMatch) < 2 {
 		return "", 0, fmt.Errorf("duration not found")
@@ -302,6 +384,57 @@ func (p *FFmpegAudioProcessor) ReadTitleAndDuration(file string) (string, float6
 	return title, duration, nil
 }
 
+// ReadChapters reads the chapters embedded in a media file using ffprobe.
+// Returns no chapters if the file has none.
+func (p *FFmpegAudioProcessor) ReadChapters(file string) ([]ChapterTiming, error) {
+	probeCmd := p.Command.Create(
+		"ffprobe",
+		"-v",
+		"error",
+		"-print_format",
+		"json",
+		"-show_chapters",
+		file,
+	)
+
+	var stdout, stderr bytes.Buffer
+
+	if err := probeCmd.Run(&stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("failed to read chapters of file %s: %w\n%s", file, err, stderr.String())
+	}
+
+	var probe struct {
+		Chapters []struct {
+			StartTime string            `json:"start_time"`
+			EndTime   string            `json:"end_time"`
+			Tags      map[string]string `json:"tags"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &probe); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe output for file %s: %w", file, err)
+	}
+
+	chapters := make([]ChapterTiming, 0, len(probe.Chapters))
+	for i, chapter := range probe.Chapters {
+		start, err := strconv.ParseFloat(chapter.StartTime, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start '%s' of chapter %d in file %s", chapter.StartTime, i, file)
+		}
+		end, err := strconv.ParseFloat(chapter.EndTime, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end '%s' of chapter %d in file %s", chapter.EndTime, i, file)
+		}
+
+		title := chapter.Tags["title"]
+		if title == "" {
+			title = chapter.Tags["TITLE"]
+		}
+		chapters = append(chapters, ChapterTiming{Start: start, End: end, Title: title})
+	}
+
+	return chapters, nil
+}
+
 // WriteMetadata updates the metadata in the file
 // WriteMetadata updates the metadata in the media file
 // Creates a temporary file during the process and replaces the original file
@@ -128,6 +128,8 @@ func TestFFmpegAudioProcessor_AddMetadata(t *testing.T) {
 			"1",
 			"-c",
 			"copy",
+			"-movflags",
+			"use_metadata_tags",
 			"-metadata",
 			"title=booktitle",
 			outputFile,
@@ -206,6 +208,8 @@ type FakeCommand struct {
 	mu              sync.Mutex
 	CreatedCommands [][]string
 	Cmd             *FakeCmd
+	Stdout          string // written to stdout by the created commands
+	Stderr          string // written to stderr by the created commands
 }
 
 func (c *FakeCommand) Create(name string, args ...string) Cmd {
@@ -214,7 +218,7 @@ func (c *FakeCommand) Create(name string, args ...string) Cmd {
 
 	fullArgs := append([]string{name}, args...)
 	c.CreatedCommands = append(c.CreatedCommands, fullArgs)
-	c.Cmd = &FakeCmd{Stdout: "", Stderr: "", Executed: false}
+	c.Cmd = &FakeCmd{Stdout: c.Stdout, Stderr: c.Stderr, Executed: false}
 	return c.Cmd
 }
 
@@ -224,8 +228,10 @@ type FakeCmd struct {
 	Executed bool
 }
 
-func (c *FakeCmd) Run(_, _ *bytes.Buffer) error {
+func (c *FakeCmd) Run(stdout, stderr *bytes.Buffer) error {
 	c.Executed = true
+	stdout.WriteString(c.Stdout)
+	stderr.WriteString(c.Stderr)
 	return nil
 }
 
@@ -233,3 +239,68 @@ func (c *FakeCmd) RunI(_ *bytes.Reader, _, _ *bytes.Buffer) error {
 	c.Executed = true
 	return nil
 }
+
+func TestFFmpegAudioProcessor_Cut(t *testing.T) {
+	fakeCommand := FakeCommand{}
+	processor := &FFmpegAudioProcessor{Command: &fakeCommand}
+
+	err := processor.Cut("rip.flac", 310.4933, 600, "cut/002 rip.flac")
+	require.NoError(t, err)
+
+	require.Equal(
+		t,
+		[]string{
+			"ffmpeg", "-i", "rip.flac", "-ss", "310.493", "-to", "600.000",
+			"-map_metadata", "-1", "-c", "copy", "-vn", "cut/002 rip.flac",
+		},
+		fakeCommand.CreatedCommands[0],
+	)
+}
+
+func TestFFmpegAudioProcessor_ReadChapters(t *testing.T) {
+	fakeCommand := FakeCommand{Stdout: `{
+    "chapters": [
+        {"id": 0, "time_base": "1/1000", "start": 0, "start_time": "0.000000", "end": 60000, "end_time": "60.000000", "tags": {"title": "Intro"}},
+        {"id": 1, "time_base": "1/1000", "start": 60000, "start_time": "60.000000", "end": 121500, "end_time": "121.500000", "tags": {"title": "Intermezzo"}}
+    ]
+}`}
+	processor := &FFmpegAudioProcessor{Command: &fakeCommand}
+
+	chapters, err := processor.ReadChapters("book.m4b")
+	require.NoError(t, err)
+
+	require.Equal(
+		t,
+		[]string{"ffprobe", "-v", "error", "-print_format", "json", "-show_chapters", "book.m4b"},
+		fakeCommand.CreatedCommands[0],
+	)
+	require.Equal(t, []ChapterTiming{
+		{Start: 0, End: 60, Title: "Intro"},
+		{Start: 60, End: 121.5, Title: "Intermezzo"},
+	}, chapters)
+}
+
+func TestFFmpegAudioProcessor_DetectSilence(t *testing.T) {
+	fakeCommand := FakeCommand{Stderr: `Input #0, mp3, from 'book.mp3':
+  Duration: 00:20:00.00, start: 0.000000, bitrate: 64 kb/s
+[silencedetect @ 0x55d5] silence_start: -0.0120
+[silencedetect @ 0x55d5] silence_end: 1.2 | silence_duration: 1.212
+[silencedetect @ 0x55d5] silence_start: 598.5
+[silencedetect @ 0x55d5] silence_end: 601.25 | silence_duration: 2.75
+[silencedetect @ 0x55d5] silence_start: 1198
+`}
+	processor := &FFmpegAudioProcessor{Command: &fakeCommand}
+
+	silences, err := processor.DetectSilence("book.mp3", "-35dB", 1.5)
+	require.NoError(t, err)
+
+	require.Equal(
+		t,
+		[]string{
+			"ffmpeg", "-hide_banner", "-nostats", "-i", "book.mp3",
+			"-af", "silencedetect=noise=-35dB:d=1.5", "-f", "null", "-",
+		},
+		fakeCommand.CreatedCommands[0],
+	)
+	require.Equal(t, []Silence{{Start: 0, End: 1.2}, {Start: 598.5, End: 601.25}}, silences)
+}
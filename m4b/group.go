@@ -0,0 +1,119 @@
+package m4b
+
+import (
+	"fmt"
+	"path"
+	"slices"
+)
+
+// Grouping modes, used in ProjectConfig.GroupBy
+const (
+	GroupByAlbum     = "album"
+	GroupByAlbumDisc = "album+disc"
+	GroupByDirectory = "directory"
+)
+
+var groupByModes = []string{GroupByAlbum, GroupByAlbumDisc, GroupByDirectory}
+
+// trackGroup is a subset of the tracks of a project that becomes its own project.
+type trackGroup struct {
+	label string // shown in check, e.g. "album=Der Hobbit, disc=2"
+	// suffix distinguishes the filenames of groups that would otherwise share one
+	suffix string
+	tracks []Track
+}
+
+func validateGroupBy(groupBy string) error {
+	if groupBy != "" && !slices.Contains(groupByModes, groupBy) {
+		return fmt.Errorf("unknown groupBy '%s', expected one of %v", groupBy, groupByModes)
+	}
+	return nil
+}
+
+// Group returns the label of the group the project was split off by groupBy.
+// It is empty for projects that were not split.
+func (p *Project) Group() string {
+	return p.group
+}
+
+// Split divides the tracks of the project according to Config.GroupBy into
+// separate projects, one per group, in the order the groups first appear.
+// Without groupBy, or if all tracks fall into one group, it returns p.
+func (p *Project) Split() ([]*Project, error) {
+	if p.Config.GroupBy == "" {
+		return []*Project{p}, nil
+	}
+
+	tracks, err := p.Tracks()
+	if err != nil {
+		return nil, fmt.Errorf("could not load audio files: %w", err)
+	}
+
+	groups, err := groupTracks(tracks, p.Config.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(groups) < 2 {
+		return []*Project{p}, nil
+	}
+
+	projects := make([]*Project, 0, len(groups))
+	for _, group := range groups {
+		project, err := NewProjectWithDeps(p.Config, p.deps)
+		if err != nil {
+			return nil, err
+		}
+		project.tracks = group.tracks
+		project.group = group.label
+		project.groupSuffix = group.suffix
+		projects = append(projects, project)
+	}
+
+	// name collisions are resolved by Filename, so projects with missing
+	// tags can still be created and checked
+	for _, project := range projects {
+		for _, sibling := range projects {
+			if sibling != project {
+				project.siblings = append(project.siblings, sibling)
+			}
+		}
+	}
+
+	return projects, nil
+}
+
+func groupTracks(tracks []Track, groupBy string) ([]trackGroup, error) {
+	var groups []trackGroup
+
+	for _, track := range tracks {
+		tags, _, err := track.Metadata()
+		if err != nil {
+			return nil, err
+		}
+
+		var group trackGroup
+		switch groupBy {
+		case GroupByAlbum:
+			group.label = "album=" + tags["album"]
+			group.suffix = tags["album"]
+		case GroupByAlbumDisc:
+			disc, _ := track.DiscNumber()
+			group.label = fmt.Sprintf("album=%s, disc=%d", tags["album"], disc)
+			group.suffix = fmt.Sprintf("Disc %d", disc)
+		case GroupByDirectory:
+			dir := path.Dir(track.RelativePath())
+			group.label = "directory=" + dir
+			group.suffix = dir
+		}
+
+		i := slices.IndexFunc(groups, func(g trackGroup) bool { return g.label == group.label })
+		if i < 0 {
+			groups = append(groups, group)
+			i = len(groups) - 1
+		}
+		groups[i].tracks = append(groups[i].tracks, track)
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,116 @@
+package m4b_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+var groupData = map[string]m4b.FileData{
+	"/test/CD1/1.flac":   {Title: "1", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Hobbit\nartist=Tolkien\ndisc=1\ntrack=1"},
+	"/test/CD1/2.flac":   {Title: "2", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Hobbit\nartist=Tolkien\ndisc=1\ntrack=2"},
+	"/test/CD2/1.flac":   {Title: "3", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Hobbit\nartist=Tolkien\ndisc=2\ntrack=1"},
+	"/test/Extra/1.flac": {Title: "4", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Silmarillion\nartist=Tolkien\ndisc=2\ntrack=1"},
+}
+
+func TestSplit(t *testing.T) {
+	files := []string{"/test/CD1/1.flac", "/test/CD1/2.flac", "/test/CD2/1.flac", "/test/Extra/1.flac"}
+
+	tests := []struct {
+		name      string
+		groupBy   string
+		groups    []string
+		filenames []string
+		tracks    []int
+	}{
+		{
+			name:      "no grouping",
+			groups:    []string{""},
+			filenames: []string{"Tolkien/Hobbit/Hobbit.m4b"},
+			tracks:    []int{4},
+		},
+		{
+			name:      "album",
+			groupBy:   "album",
+			groups:    []string{"album=Hobbit", "album=Silmarillion"},
+			filenames: []string{"Tolkien/Hobbit/Hobbit.m4b", "Tolkien/Silmarillion/Silmarillion.m4b"},
+			tracks:    []int{3, 1},
+		},
+		{
+			name:    "album and disc",
+			groupBy: "album+disc",
+			groups:  []string{"album=Hobbit, disc=1", "album=Hobbit, disc=2", "album=Silmarillion, disc=2"},
+			filenames: []string{
+				"Tolkien/Hobbit/Hobbit - Disc 1.m4b",
+				"Tolkien/Hobbit/Hobbit - Disc 2.m4b",
+				"Tolkien/Silmarillion/Silmarillion.m4b",
+			},
+			tracks: []int{2, 1, 1},
+		},
+		{
+			name:    "directory",
+			groupBy: "directory",
+			groups:  []string{"directory=CD1", "directory=CD2", "directory=Extra"},
+			filenames: []string{
+				"Tolkien/Hobbit/Hobbit - CD1.m4b",
+				"Tolkien/Hobbit/Hobbit - CD2.m4b",
+				"Tolkien/Silmarillion/Silmarillion.m4b",
+			},
+			tracks: []int{2, 1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := m4b.ProjectConfig{ProjectPath: "/test", GroupBy: tt.groupBy}
+			project, err := m4b.NewProjectWithDeps(config, fakeDeps(groupData, files))
+			require.NoError(t, err)
+
+			projects, err := project.Split()
+			require.NoError(t, err)
+			require.Len(t, projects, len(tt.groups))
+
+			for i, p := range projects {
+				require.Equal(t, tt.groups[i], p.Group())
+
+				filename, err := p.Filename()
+				require.NoError(t, err)
+				rel, err := filepath.Rel(p.Config.OutputPath(), filename)
+				require.NoError(t, err)
+				require.Equal(t, tt.filenames[i], rel)
+
+				tracks, err := p.Tracks()
+				require.NoError(t, err)
+				require.Len(t, tracks, tt.tracks[i])
+			}
+		})
+	}
+}
+
+func TestSplit_InvalidGroupBy(t *testing.T) {
+	config := m4b.ProjectConfig{GroupBy: "artist"}
+	require.ErrorContains(t, config.Validate(), "unknown groupBy 'artist'")
+}
+
+func TestSplit_MissingTags(t *testing.T) {
+	data := map[string]m4b.FileData{
+		"/test/CD1/1.flac": {Title: "1", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Hobbit\nartist=Tolkien\ndisc=1"},
+		"/test/CD2/1.flac": {Title: "2", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Hobbit\ndisc=2"},
+	}
+	config := m4b.ProjectConfig{ProjectPath: "/test", GroupBy: "directory"}
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, []string{"/test/CD1/1.flac", "/test/CD2/1.flac"}))
+	require.NoError(t, err)
+
+	projects, err := project.Split()
+	require.NoError(t, err)
+	require.Len(t, projects, 2)
+
+	filename, err := projects[0].Filename()
+	require.NoError(t, err)
+	require.Equal(t, "Hobbit.m4b", filepath.Base(filename))
+
+	_, err = projects[1].Filename()
+	require.ErrorContains(t, err, "no artist found in metadata")
+}
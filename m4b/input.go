@@ -0,0 +1,67 @@
+package m4b
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/achwo/narr/utils"
+)
+
+// InputConfig selects the audio files of a project.
+type InputConfig struct {
+	// Extensions of the audio files, matched case-insensitively.
+	// Defaults to m4a, mp3 and flac. Formats that can not be concatenated
+	// into an m4b as they are, e.g. wav or opus, require shouldConvert.
+	Extensions []string `yaml:"extensions,omitempty"`
+	// Include and Exclude are globs over the path relative to the project,
+	// e.g. "Sample" or "Bonus/**". Patterns without / match file and directory
+	// names at any depth. Excluded directories are skipped entirely.
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+	// MaxDepth limits how deep subdirectories are searched. 1 only uses the
+	// project directory itself, 0 is unlimited.
+	MaxDepth int `yaml:"maxDepth,omitempty"`
+}
+
+// Validate checks the globs and the depth.
+func (c *InputConfig) Validate() error {
+	if c.MaxDepth < 0 {
+		return errors.New("maxDepth must not be negative")
+	}
+
+	for _, pattern := range slices.Concat(c.Include, c.Exclude) {
+		if _, err := utils.MatchGlob(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob '%s': %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// copyableExtensions are the formats that can be concatenated into an m4b
+// without converting them first.
+var copyableExtensions = []string{".m4a", ".m4b", ".mp3", ".flac", ".aac"}
+
+// conversionRequired returns the first configured extension that can not be
+// concatenated without conversion, or "" if there is none.
+func (c *InputConfig) conversionRequired() string {
+	for _, ext := range c.Extensions {
+		normalized := "." + strings.TrimPrefix(strings.ToLower(ext), ".")
+		if !slices.Contains(copyableExtensions, normalized) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// AudioFileProvider returns a provider that finds the audio files selected by the config.
+func (c *InputConfig) AudioFileProvider() *utils.OSAudioFileProvider {
+	return &utils.OSAudioFileProvider{
+		Extensions: c.Extensions,
+		Include:    c.Include,
+		Exclude:    c.Exclude,
+		MaxDepth:   c.MaxDepth,
+	}
+}
@@ -5,6 +5,8 @@ type FileData struct {
 	Title    string  // Title of the audio file
 	Duration float64 // Duration in seconds
 	Metadata string  // Additional metadata
+	Chapters []ChapterTiming
+	Silences []Silence
 }
 
 // NullAudioProcessor implements a no-op audio processor that returns empty/nil values.
@@ -21,6 +23,12 @@ func (p *NullAudioProcessor) ToM4A(files []string, outputPath string) ([]string,
 	return nil, nil
 }
 
+// Cut is a no-op implementation that returns nil.
+// It simulates cutting a segment out of an audio file.
+func (p *NullAudioProcessor) Cut(file string, start float64, end float64, outputFile string) error {
+	return nil
+}
+
 // Concat is a no-op implementation that returns empty values.
 // It simulates concatenating multiple audio files into a single file.
 func (p *NullAudioProcessor) Concat(files []string, filelistPath string, outputPath string) (string, error) {
@@ -66,3 +74,13 @@ func (p *NullAudioProcessor) ReadMetadata(file string) (string, error) {
 	}
 	return p.Data[file].Metadata, nil
 }
+
+// ReadChapters returns the preconfigured chapters for a file
+func (p *NullAudioProcessor) ReadChapters(file string) ([]ChapterTiming, error) {
+	return p.Data[file].Chapters, nil
+}
+
+// DetectSilence returns the preconfigured silences for a file
+func (p *NullAudioProcessor) DetectSilence(file string, noise string, minLength float64) ([]Silence, error) {
+	return p.Data[file].Silences, nil
+}
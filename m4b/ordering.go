@@ -0,0 +1,262 @@
+package m4b
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/achwo/narr/utils"
+)
+
+// Ordering strategies, used in OrderingConfig.By
+const (
+	OrderByTags     = "tags"
+	OrderByFilename = "filename"
+	OrderByMtime    = "mtime"
+	OrderByPlaylist = "playlist"
+	OrderByExplicit = "explicit"
+)
+
+var orderingStrategies = []string{OrderByTags, OrderByFilename, OrderByMtime, OrderByPlaylist, OrderByExplicit}
+
+var playlistExtensions = []string{".m3u", ".m3u8"}
+
+// OrderingConfig configures the order of the tracks in the audiobook.
+type OrderingConfig struct {
+	// By selects the strategy: tags (disc, then track number, then filename;
+	// the default), filename, mtime, playlist or explicit.
+	By string `yaml:"by,omitempty"`
+	// Playlist is the .m3u/.m3u8 file for the playlist strategy, relative to the
+	// project. Defaults to the first playlist in the project folder.
+	Playlist string `yaml:"playlist,omitempty"`
+	// Files lists filenames or globs for the explicit strategy, relative to the
+	// project. Tracks matching the same entry are ordered by filename.
+	Files []string `yaml:"files,omitempty"`
+	// Collation configures how filenames are compared, e.g. whether roman
+	// numerals and spelled-out numbers count as numbers.
+	Collation utils.Collator `yaml:"collation,omitempty"`
+}
+
+// Validate checks the strategy and the globs of the explicit order.
+func (c *OrderingConfig) Validate() error {
+	if c.By != "" && !slices.Contains(orderingStrategies, c.By) {
+		return fmt.Errorf("unknown ordering '%s', expected one of %v", c.By, orderingStrategies)
+	}
+
+	if err := c.Collation.Validate(); err != nil {
+		return fmt.Errorf("collation invalid: %w", err)
+	}
+
+	if c.By == OrderByExplicit && len(c.Files) == 0 {
+		return errors.New("explicit ordering requires files")
+	}
+
+	for _, file := range c.Files {
+		if _, err := utils.MatchGlob(file, ""); err != nil {
+			return fmt.Errorf("invalid glob '%s': %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+// orderTracks sorts the tracks according to the config and records on every
+// track the key that decided its position. projectDir is the directory the
+// playlist and explicit files are relative to.
+func orderTracks(tracks []Track, config OrderingConfig, projectDir string) error {
+	switch config.By {
+	case OrderByFilename:
+		for i := range tracks {
+			tracks[i].orderKey = "filename " + tracks[i].RelativePath()
+		}
+		slices.SortStableFunc(tracks, compareFilenames(config.Collation))
+		return nil
+	case OrderByMtime:
+		return orderByMtime(tracks, config.Collation)
+	case OrderByPlaylist:
+		return orderByPlaylist(tracks, config.Playlist, projectDir)
+	case OrderByExplicit:
+		return orderByExplicit(tracks, config.Files, config.Collation)
+	default:
+		return orderByTags(tracks, config.Collation)
+	}
+}
+
+func compareFilenames(collator utils.Collator) func(a, b Track) int {
+	return func(a, b Track) int {
+		return collator.Compare(a.File, b.File)
+	}
+}
+
+// orderByTags sorts by disc and track number, falling back to the filename.
+func orderByTags(tracks []Track, collator utils.Collator) error {
+	for i := range tracks {
+		track := &tracks[i]
+		disc, discExists := track.DiscNumber()
+		number, numberExists := track.TrackNumber()
+
+		switch {
+		case discExists && numberExists && track.discDir != "":
+			track.orderKey = fmt.Sprintf("disc %d (from %s/), track %d", disc, track.discDir, number)
+		case discExists && numberExists:
+			track.orderKey = fmt.Sprintf("disc %d, track %d", disc, number)
+		case numberExists:
+			track.orderKey = fmt.Sprintf("track %d", number)
+		default:
+			track.orderKey = "filename " + track.RelativePath()
+		}
+	}
+
+	slices.SortStableFunc(tracks, sortTracks(collator))
+	return nil
+}
+
+// duplicateTrackNumbers returns an error if two tracks share disc and track
+// number, as their order would be arbitrary. Tracks without a disc share
+// disc 0; disc detection gives the discs of multi-folder rips a number.
+func duplicateTrackNumbers(tracks []Track) error {
+	type position struct{ disc, track int }
+	seen := map[position]string{}
+
+	for _, track := range tracks {
+		disc, _ := track.DiscNumber()
+		number, exists := track.TrackNumber()
+		if !exists {
+			continue
+		}
+
+		pos := position{disc, number}
+		if other, exists := seen[pos]; exists {
+			return fmt.Errorf("duplicate %s: %s and %s", track.orderKey, other, track.RelativePath())
+		}
+		seen[pos] = track.RelativePath()
+	}
+
+	return nil
+}
+
+func orderByMtime(tracks []Track, collator utils.Collator) error {
+	for i := range tracks {
+		info, err := os.Stat(tracks[i].File)
+		if err != nil {
+			return fmt.Errorf("could not get modification time: %w", err)
+		}
+		tracks[i].modTime = info.ModTime()
+		tracks[i].orderKey = "mtime " + info.ModTime().Format("2006-01-02 15:04:05.000")
+	}
+
+	slices.SortStableFunc(tracks, func(a, b Track) int {
+		return cmp.Or(a.modTime.Compare(b.modTime), collator.Compare(a.File, b.File))
+	})
+	return nil
+}
+
+func orderByPlaylist(tracks []Track, playlist string, projectDir string) error {
+	if playlist == "" {
+		found, err := findPlaylist(projectDir)
+		if err != nil {
+			return err
+		}
+		playlist = found
+	} else if !filepath.IsAbs(playlist) {
+		playlist = filepath.Join(projectDir, playlist)
+	}
+
+	entries, err := readPlaylist(playlist)
+	if err != nil {
+		return fmt.Errorf("could not read playlist %s: %w", playlist, err)
+	}
+
+	positions := map[string]int{}
+	for i := range tracks {
+		index := slices.Index(entries, filepath.Clean(tracks[i].File))
+		if index < 0 {
+			return fmt.Errorf("track %s is not in playlist %s", tracks[i].RelativePath(), filepath.Base(playlist))
+		}
+		positions[tracks[i].File] = index
+		tracks[i].orderKey = fmt.Sprintf("playlist entry %d", index+1)
+	}
+
+	slices.SortStableFunc(tracks, func(a, b Track) int {
+		return cmp.Compare(positions[a.File], positions[b.File])
+	})
+	return nil
+}
+
+func findPlaylist(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("could not read directory %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && slices.Contains(playlistExtensions, strings.ToLower(filepath.Ext(entry.Name()))) {
+			return filepath.Join(dir, entry.Name()), nil
+		}
+	}
+
+	return "", fmt.Errorf("no playlist found in %s", dir)
+}
+
+// readPlaylist returns the cleaned absolute paths of the entries of an m3u
+// playlist. Relative entries are resolved against the playlist's directory.
+func readPlaylist(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry := filepath.FromSlash(strings.ReplaceAll(line, `\`, "/"))
+		if !filepath.IsAbs(entry) {
+			entry = filepath.Join(filepath.Dir(file), entry)
+		}
+		entries = append(entries, filepath.Clean(entry))
+	}
+
+	return entries, scanner.Err()
+}
+
+func orderByExplicit(tracks []Track, files []string, collator utils.Collator) error {
+	positions := map[string]int{}
+
+	for i := range tracks {
+		rel := tracks[i].RelativePath()
+		index := -1
+		for j, pattern := range files {
+			matched, err := utils.MatchGlob(pattern, rel)
+			if err != nil {
+				return fmt.Errorf("invalid glob '%s': %w", pattern, err)
+			}
+			if matched || pattern == rel {
+				index = j
+				break
+			}
+		}
+
+		if index < 0 {
+			return fmt.Errorf("track %s is not matched by the explicit ordering", rel)
+		}
+
+		positions[tracks[i].File] = index
+		tracks[i].orderKey = fmt.Sprintf("explicit entry %d '%s'", index+1, files[index])
+	}
+
+	slices.SortStableFunc(tracks, func(a, b Track) int {
+		return cmp.Or(cmp.Compare(positions[a.File], positions[b.File]), collator.Compare(a.File, b.File))
+	})
+	return nil
+}
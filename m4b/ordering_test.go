@@ -0,0 +1,192 @@
+package m4b_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/achwo/narr/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrdering(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "CD1"), 0755))
+
+	intro := filepath.Join(dir, "intro.mp3")
+	one := filepath.Join(dir, "CD1", "1.mp3")
+	ten := filepath.Join(dir, "CD1", "10.mp3")
+	two := filepath.Join(dir, "CD1", "2.mp3")
+
+	now := time.Now()
+	for i, file := range []string{ten, intro, two, one} {
+		require.NoError(t, os.WriteFile(file, []byte{}, 0644))
+		mtime := now.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, os.Chtimes(file, mtime, mtime))
+	}
+
+	playlist := "#EXTM3U\n#EXTINF:10,Intro\nintro.mp3\nCD1/2.mp3\nCD1\\1.mp3\n" + ten + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "book.m3u"), []byte(playlist), 0644))
+
+	data := map[string]m4b.FileData{
+		intro: {Duration: 10, Metadata: ";FFMETADATA1\ntrack=4"},
+		one:   {Duration: 10, Metadata: ";FFMETADATA1\ntrack=3"},
+		ten:   {Duration: 10, Metadata: ";FFMETADATA1\ntrack=1"},
+		two:   {Duration: 10, Metadata: ";FFMETADATA1\ntrack=2"},
+	}
+
+	tests := []struct {
+		name     string
+		ordering m4b.OrderingConfig
+		expected []string
+		keys     []string
+	}{
+		{
+			name:     "tags by default",
+			expected: []string{ten, two, one, intro},
+			keys:     []string{"disc 1 (from CD1/), track 1", "disc 1 (from CD1/), track 2", "disc 1 (from CD1/), track 3", "track 4"},
+		},
+		{
+			name:     "filename",
+			ordering: m4b.OrderingConfig{By: "filename"},
+			expected: []string{one, two, ten, intro},
+			keys:     []string{"filename CD1/1.mp3", "filename CD1/2.mp3", "filename CD1/10.mp3", "filename intro.mp3"},
+		},
+		{
+			name:     "mtime",
+			ordering: m4b.OrderingConfig{By: "mtime"},
+			expected: []string{ten, intro, two, one},
+		},
+		{
+			name:     "playlist",
+			ordering: m4b.OrderingConfig{By: "playlist"},
+			expected: []string{intro, two, one, ten},
+			keys:     []string{"playlist entry 1", "playlist entry 2", "playlist entry 3", "playlist entry 4"},
+		},
+		{
+			name:     "explicit",
+			ordering: m4b.OrderingConfig{By: "explicit", Files: []string{"intro.mp3", "CD1/*"}},
+			expected: []string{intro, one, two, ten},
+			keys: []string{
+				"explicit entry 1 'intro.mp3'",
+				"explicit entry 2 'CD1/*'",
+				"explicit entry 2 'CD1/*'",
+				"explicit entry 2 'CD1/*'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := m4b.ProjectConfig{ProjectPath: dir, Ordering: tt.ordering}
+			project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, []string{intro, one, ten, two}))
+			require.NoError(t, err)
+
+			tracks, err := project.Tracks()
+			require.NoError(t, err)
+
+			files := make([]string, 0, len(tracks))
+			keys := make([]string, 0, len(tracks))
+			for _, track := range tracks {
+				files = append(files, track.File)
+				keys = append(keys, track.OrderKey())
+			}
+			require.Equal(t, tt.expected, files)
+			if tt.keys != nil {
+				require.Equal(t, tt.keys, keys)
+			}
+		})
+	}
+}
+
+func TestOrdering_Errors(t *testing.T) {
+	dir := t.TempDir()
+	one := filepath.Join(dir, "1.mp3")
+	two := filepath.Join(dir, "2.mp3")
+	data := map[string]m4b.FileData{
+		one: {Duration: 10, Metadata: ";FFMETADATA1\ndisc=1\ntrack=1"},
+		two: {Duration: 10, Metadata: ";FFMETADATA1\ndisc=1\ntrack=1"},
+	}
+	deps := fakeDeps(data, []string{one, two})
+
+	project, err := m4b.NewProjectWithDeps(m4b.ProjectConfig{ProjectPath: dir}, deps)
+	require.NoError(t, err)
+	require.EqualError(t, project.ValidateOrder(), "ambiguous track order: duplicate disc 1, track 1: 1.mp3 and 2.mp3")
+
+	// tracks without disc share one disc
+	noDisc := map[string]m4b.FileData{
+		one: {Duration: 10, Metadata: ";FFMETADATA1\ntrack=3"},
+		two: {Duration: 10, Metadata: ";FFMETADATA1\ntrack=3"},
+	}
+	project, err = m4b.NewProjectWithDeps(m4b.ProjectConfig{ProjectPath: dir}, fakeDeps(noDisc, []string{one, two}))
+	require.NoError(t, err)
+	require.EqualError(t, project.ValidateOrder(), "ambiguous track order: duplicate track 3: 1.mp3 and 2.mp3")
+
+	// disc detection tells the discs of multi-folder rips apart
+	cd1 := filepath.Join(dir, "CD1", "1.mp3")
+	cd2 := filepath.Join(dir, "CD2", "1.mp3")
+	discs := map[string]m4b.FileData{
+		cd1: {Duration: 10, Metadata: ";FFMETADATA1\ntrack=1"},
+		cd2: {Duration: 10, Metadata: ";FFMETADATA1\ntrack=1"},
+	}
+	project, err = m4b.NewProjectWithDeps(m4b.ProjectConfig{ProjectPath: dir}, fakeDeps(discs, []string{cd1, cd2}))
+	require.NoError(t, err)
+	require.NoError(t, project.ValidateOrder())
+
+	config := m4b.ProjectConfig{ProjectPath: dir, Ordering: m4b.OrderingConfig{By: "explicit", Files: []string{"1.mp3"}}}
+	project, err = m4b.NewProjectWithDeps(config, deps)
+	require.NoError(t, err)
+	_, err = project.Tracks()
+	require.ErrorContains(t, err, "track 2.mp3 is not matched by the explicit ordering")
+
+	config = m4b.ProjectConfig{ProjectPath: dir, Ordering: m4b.OrderingConfig{By: "playlist"}}
+	project, err = m4b.NewProjectWithDeps(config, deps)
+	require.NoError(t, err)
+	_, err = project.Tracks()
+	require.ErrorContains(t, err, "no playlist found")
+}
+
+func TestOrdering_Validate(t *testing.T) {
+	tests := []struct {
+		ordering m4b.OrderingConfig
+		err      string
+	}{
+		{m4b.OrderingConfig{By: "random"}, "unknown ordering 'random'"},
+		{m4b.OrderingConfig{By: "explicit"}, "explicit ordering requires files"},
+		{m4b.OrderingConfig{By: "explicit", Files: []string{"["}}, "invalid glob '['"},
+		{m4b.OrderingConfig{Collation: utils.Collator{Locale: "fr"}}, "unsupported locale 'fr'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			config := m4b.ProjectConfig{Ordering: tt.ordering}
+			require.ErrorContains(t, config.Validate(), tt.err)
+		})
+	}
+}
+
+func TestOrdering_Collation(t *testing.T) {
+	files := []string{"/test/Teil X.mp3", "/test/Teil II.mp3", "/test/Teil IX.mp3", "/test/Teil V.mp3"}
+	data := map[string]m4b.FileData{}
+	for _, file := range files {
+		data[file] = m4b.FileData{Duration: 10, Metadata: ";FFMETADATA1"}
+	}
+
+	config := m4b.ProjectConfig{
+		ProjectPath: "/test",
+		Ordering:    m4b.OrderingConfig{Collation: utils.Collator{RomanNumerals: true}},
+	}
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, files))
+	require.NoError(t, err)
+
+	tracks, err := project.Tracks()
+	require.NoError(t, err)
+
+	var sorted []string
+	for _, track := range tracks {
+		sorted = append(sorted, track.File)
+	}
+	require.Equal(t, []string{"/test/Teil II.mp3", "/test/Teil V.mp3", "/test/Teil IX.mp3", "/test/Teil X.mp3"}, sorted)
+}
@@ -0,0 +1,92 @@
+package m4b
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PathRule derives tags from the path of a track. The named groups of the
+// regex become tags, e.g. (?P<artist>[^/]+)/(?P<album>[^/]+)/(?P<track>\d+).
+// Path rules run before the metadata rules and only fill tags that are
+// missing or empty, unless overwrite is set.
+type PathRule struct {
+	Regex string `yaml:"regex"`
+	// Source is either "path" (default), the path relative to the project,
+	// "filename", the name of the file, or "project", the name of the project
+	// directory. Paths use / as separator.
+	Source    string `yaml:"source,omitempty"`
+	Overwrite bool   `yaml:"overwrite,omitempty"`
+}
+
+// Validate checks that the regex compiles and has named groups and that the
+// source is known.
+func (r *PathRule) Validate() error {
+	if r.Regex == "" {
+		return errors.New("path rule requires a regex")
+	}
+
+	regex, err := regexp.Compile(r.Regex)
+	if err != nil {
+		return fmt.Errorf("regex '%s' is invalid: %w", r.Regex, err)
+	}
+
+	hasNamedGroup := false
+	for _, name := range regex.SubexpNames() {
+		if name != "" {
+			hasNamedGroup = true
+		}
+	}
+	if !hasNamedGroup {
+		return fmt.Errorf("path rule regex '%s' requires named groups, e.g. (?P<album>...)", r.Regex)
+	}
+
+	switch r.Source {
+	case "", "path", "filename", "project":
+		return nil
+	default:
+		return fmt.Errorf("unknown path rule source: %s", r.Source)
+	}
+}
+
+// Apply matches the regex against the relative path of a track, or the
+// project directory name for source "project", and writes the named groups
+// into tags. It returns the names of the tags it set.
+func (r *PathRule) Apply(projectName string, relativePath string, tags map[string]string) ([]string, error) {
+	regex, err := regexp.Compile(r.Regex)
+	if err != nil {
+		return nil, fmt.Errorf("path rule regex '%s' is invalid: %w", r.Regex, err)
+	}
+
+	input := relativePath
+	switch r.Source {
+	case "filename":
+		input = path.Base(relativePath)
+	case "project":
+		input = projectName
+	}
+
+	matches := regex.FindStringSubmatch(input)
+	if matches == nil {
+		return nil, nil
+	}
+
+	var set []string
+	for i, name := range regex.SubexpNames() {
+		if name == "" || matches[i] == "" {
+			continue
+		}
+
+		tagName := strings.ToLower(name)
+		if tags[tagName] != "" && !r.Overwrite {
+			continue
+		}
+
+		tags[tagName] = matches[i]
+		set = append(set, tagName)
+	}
+
+	return set, nil
+}
@@ -0,0 +1,137 @@
+package m4b_test
+
+import (
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathRule_Apply(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     m4b.PathRule
+		path     string
+		tags     map[string]string
+		expected map[string]string
+	}{
+		{
+			name: "fills tags from path",
+			rule: m4b.PathRule{Regex: `^(?P<artist>[^/]+)/(?P<Album>[^/]+)/CD\d+/(?P<track>\d+) `},
+			path: "Author/03 - Title/CD1/05 Track.flac",
+			tags: map[string]string{},
+			expected: map[string]string{
+				"artist": "Author",
+				"album":  "03 - Title",
+				"track":  "05",
+			},
+		},
+		{
+			name:     "keeps existing tags",
+			rule:     m4b.PathRule{Regex: `^(?P<album>[^/]+)/`},
+			path:     "Dir/file.flac",
+			tags:     map[string]string{"album": "Tagged"},
+			expected: map[string]string{"album": "Tagged"},
+		},
+		{
+			name:     "overwrites existing tags",
+			rule:     m4b.PathRule{Regex: `^(?P<album>[^/]+)/`, Overwrite: true},
+			path:     "Dir/file.flac",
+			tags:     map[string]string{"album": "Tagged"},
+			expected: map[string]string{"album": "Dir"},
+		},
+		{
+			name:     "filename",
+			rule:     m4b.PathRule{Regex: `^(?P<track>\d+) (?P<title>.+)\.flac$`, Source: "filename"},
+			path:     "CD1/05 Track.flac",
+			tags:     map[string]string{"title": ""},
+			expected: map[string]string{"track": "05", "title": "Track"},
+		},
+		{
+			name:     "project",
+			rule:     m4b.PathRule{Regex: `^(?P<album>.+)$`, Source: "project"},
+			path:     "CD1/05 Track.flac",
+			tags:     map[string]string{},
+			expected: map[string]string{"album": "Book"},
+		},
+		{
+			name:     "no match",
+			rule:     m4b.PathRule{Regex: `^CD(?P<disc>\d+)/`},
+			path:     "05 Track.flac",
+			tags:     map[string]string{},
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.rule.Validate())
+			_, err := tt.rule.Apply("Book", tt.path, tt.tags)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, tt.tags)
+		})
+	}
+}
+
+func TestPathRule_Validate(t *testing.T) {
+	require.ErrorContains(t, (&m4b.PathRule{Regex: `(\d+)`}).Validate(), "requires named groups")
+	require.ErrorContains(t, (&m4b.PathRule{Regex: `(?P<a>x)`, Source: "dir"}).Validate(), "unknown path rule source")
+	require.ErrorContains(t, (&m4b.PathRule{}).Validate(), "requires a regex")
+}
+
+func TestPathRules_UntaggedProject(t *testing.T) {
+	data := map[string]m4b.FileData{
+		"Author/Book/CD1/02 Second.flac": {Duration: 10, Metadata: ";FFMETADATA1\n"},
+		"Author/Book/CD1/01 First.flac":  {Duration: 10, Metadata: ";FFMETADATA1\n"},
+		"Author/Book/CD2/01 Third.flac":  {Duration: 10, Metadata: ";FFMETADATA1\n"},
+	}
+
+	config := m4b.ProjectConfig{
+		PathRules: []m4b.PathRule{
+			{Regex: `^(?P<artist>[^/]+)/(?P<album>[^/]+)/CD(?P<disc>\d+)/`},
+			{Regex: `^(?P<track>\d+) (?P<title>.+)\.flac$`, Source: "filename"},
+		},
+		MetadataRules: []m4b.MetadataRule{
+			{Type: "template", Tag: "album", Template: "{{.album}} (gelesen)"},
+		},
+	}
+
+	files := []string{"Author/Book/CD2/01 Third.flac", "Author/Book/CD1/02 Second.flac", "Author/Book/CD1/01 First.flac"}
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, files))
+	require.NoError(t, err)
+
+	artist, album, err := project.ArtistAndBookTitle()
+	require.NoError(t, err)
+	require.Equal(t, "Author", artist)
+	require.Equal(t, "Book (gelesen)", album)
+
+	tracks, err := project.Tracks()
+	require.NoError(t, err)
+	require.Equal(t, "Author/Book/CD1/01 First.flac", tracks[0].File)
+	require.Equal(t, "Author/Book/CD1/02 Second.flac", tracks[1].File)
+	require.Equal(t, "Author/Book/CD2/01 Third.flac", tracks[2].File)
+
+	metadata, err := project.Metadata()
+	require.NoError(t, err)
+	require.Equal(t, ";FFMETADATA1\nartist=Author\nalbum=Book (gelesen)\ntitle=First", metadata)
+}
+
+func TestPathRules_EscapedInMetadata(t *testing.T) {
+	data := map[string]m4b.FileData{
+		"Author/Teil 1; Band 2/01.flac": {Duration: 10, Metadata: ";FFMETADATA1\n"},
+	}
+	config := m4b.ProjectConfig{
+		PathRules: []m4b.PathRule{{Regex: `^(?P<artist>[^/]+)/(?P<album>[^/]+)/`}},
+	}
+
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, []string{"Author/Teil 1; Band 2/01.flac"}))
+	require.NoError(t, err)
+
+	metadata, err := project.Metadata()
+	require.NoError(t, err)
+	require.Equal(t, ";FFMETADATA1\nartist=Author\nalbum=Teil 1\\; Band 2", metadata)
+
+	_, album, err := project.ArtistAndBookTitle()
+	require.NoError(t, err)
+	require.Equal(t, "Teil 1; Band 2", album)
+}
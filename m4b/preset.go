@@ -0,0 +1,197 @@
+package m4b
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// presetFileExts are the extensions of preset files, in the order they are
+// looked up by name.
+var presetFileExts = []string{".yaml", ".yml"}
+
+// Preset is a named, reusable set of rules that can be referenced from the
+// presets list of a ProjectConfig.
+type Preset struct {
+	Name          string         `yaml:"-"`
+	Path          string         `yaml:"-"`
+	Description   string         `yaml:"description,omitempty"`
+	MetadataRules []MetadataRule `yaml:"metadataRules,omitempty"`
+	ChapterRules  []ChapterRule  `yaml:"chapterRules,omitempty"`
+}
+
+// Validate checks all rules of the preset.
+func (p *Preset) Validate() error {
+	for _, rule := range p.MetadataRules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("metadata rule invalid: %w", err)
+		}
+	}
+
+	for _, rule := range p.ChapterRules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("chapter rule invalid: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// PresetDir returns the directory containing the global presets
+// (~/.config/narr/presets).
+func PresetDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user home dir: %w", err)
+	}
+
+	return filepath.Join(home, ".config", "narr", "presets"), nil
+}
+
+// ListPresets returns all presets from the global preset directory, sorted by name.
+// A missing preset directory results in an empty list.
+func ListPresets() ([]Preset, error) {
+	dir, err := PresetDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read preset dir %s: %w", dir, err)
+	}
+
+	var presets []Preset
+
+	for _, entry := range entries {
+		if entry.IsDir() || !slices.Contains(presetFileExts, filepath.Ext(entry.Name())) {
+			continue
+		}
+
+		// name.yaml wins over name.yml, as in LoadPreset
+		path, err := presetPath(dir, strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		if err != nil {
+			return nil, err
+		}
+		if path != filepath.Join(dir, entry.Name()) {
+			continue
+		}
+
+		preset, err := readPreset(path)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, *preset)
+	}
+
+	slices.SortFunc(presets, func(a, b Preset) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return presets, nil
+}
+
+// LoadPreset resolves a preset reference and reads it.
+// References that look like a path (containing a path separator or ending in
+// .yaml/.yml) are resolved relative to baseDir, everything else is looked up
+// by name in the global preset directory.
+func LoadPreset(ref string, baseDir string) (*Preset, error) {
+	if ref == "" {
+		return nil, errors.New("preset reference is empty")
+	}
+
+	var path string
+
+	if isPresetPath(ref) {
+		path = ref
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+	} else {
+		dir, err := PresetDir()
+		if err != nil {
+			return nil, err
+		}
+		path, err = presetPath(dir, ref)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return readPreset(path)
+}
+
+// presetPath returns the file of the preset with the given name in dir,
+// trying the extensions in order. If there is none, the path with the first
+// extension is returned, so reading it reports the missing file.
+func presetPath(dir string, name string) (string, error) {
+	for _, ext := range presetFileExts {
+		path := filepath.Join(dir, name+ext)
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("could not read preset %s: %w", path, err)
+		}
+	}
+	return filepath.Join(dir, name+presetFileExts[0]), nil
+}
+
+func isPresetPath(ref string) bool {
+	ext := filepath.Ext(ref)
+	return strings.ContainsRune(ref, '/') ||
+		strings.ContainsRune(ref, filepath.Separator) ||
+		slices.Contains(presetFileExts, ext)
+}
+
+func readPreset(path string) (*Preset, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read preset %s: %w", path, err)
+	}
+
+	var preset Preset
+	if err = yaml.Unmarshal(bytes, &preset); err != nil {
+		return nil, fmt.Errorf("could not unmarshal preset %s: %w", path, err)
+	}
+
+	preset.Path = path
+	preset.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	if err = preset.Validate(); err != nil {
+		return nil, fmt.Errorf("preset %s invalid: %w", preset.Name, err)
+	}
+
+	return &preset, nil
+}
+
+// expandPresets prepends the rules of all referenced presets, in order, to the
+// rules of the config. Relative preset paths are resolved against baseDir.
+func (c *ProjectConfig) expandPresets(baseDir string) error {
+	var metadataRules []MetadataRule
+	var chapterRules []ChapterRule
+
+	for _, ref := range c.Presets {
+		preset, err := LoadPreset(ref, baseDir)
+		if err != nil {
+			return fmt.Errorf("could not load preset '%s': %w", ref, err)
+		}
+
+		metadataRules = append(metadataRules, preset.MetadataRules...)
+		chapterRules = append(chapterRules, preset.ChapterRules...)
+	}
+
+	c.MetadataRules = slices.Concat(metadataRules, c.MetadataRules)
+	c.ChapterRules = slices.Concat(chapterRules, c.ChapterRules)
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package m4b_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresets_ExpandBeforeLocalRules(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	presetDir := filepath.Join(home, ".config", "narr", "presets")
+	require.NoError(t, os.MkdirAll(presetDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(presetDir, "drei-fragezeichen.yaml"), []byte(`
+description: Zero-pad episode numbers
+metadataRules:
+  - tag: album
+    type: regex
+    regex: "Folge (\\d): (.*)"
+    format: "Folge 00%s: %s"
+`), 0644))
+
+	projectDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "strip.yaml"), []byte(`
+chapterRules:
+  - regex: "(.*) \\(CD \\d\\)"
+    format: "%s"
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "narr.yaml"), []byte(`
+presets: [drei-fragezeichen, ./strip.yaml]
+metadataRules:
+  - tag: genre
+    type: set
+    value: Hörspiel
+chapterRules:
+  - regex: "Kapitel (.*)"
+    format: "Chapter %s"
+`), 0644))
+
+	projects, err := m4b.NewProjectsFromPath(projectDir)
+	require.NoError(t, err)
+	require.Len(t, projects, 1)
+
+	config := projects[0].Config
+	require.Len(t, config.MetadataRules, 2)
+	require.Equal(t, "album", config.MetadataRules[0].Tag)
+	require.Equal(t, "genre", config.MetadataRules[1].Tag)
+
+	require.Len(t, config.ChapterRules, 2)
+	require.Equal(t, "(.*) \\(CD \\d\\)", config.ChapterRules[0].Regex)
+	require.Equal(t, "Kapitel (.*)", config.ChapterRules[1].Regex)
+}
+
+func TestPresets_Missing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	projectDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "narr.yaml"), []byte("presets: [unknown]\n"), 0644))
+
+	_, err := m4b.NewProjectsFromPath(projectDir)
+	require.ErrorContains(t, err, "could not load preset 'unknown'")
+}
+
+func TestListPresets(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	presets, err := m4b.ListPresets()
+	require.NoError(t, err)
+	require.Empty(t, presets)
+
+	presetDir := filepath.Join(home, ".config", "narr", "presets")
+	require.NoError(t, os.MkdirAll(presetDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(presetDir, "b.yaml"), []byte("description: second\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(presetDir, "a.yaml"), []byte("description: first\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(presetDir, "c.yml"), []byte("description: third\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(presetDir, "b.yml"), []byte("description: shadowed\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(presetDir, "notes.txt"), []byte("ignored"), 0644))
+
+	presets, err = m4b.ListPresets()
+	require.NoError(t, err)
+	require.Len(t, presets, 3)
+	require.Equal(t, "a", presets[0].Name)
+	require.Equal(t, "first", presets[0].Description)
+	require.Equal(t, "b", presets[1].Name)
+	require.Equal(t, "second", presets[1].Description)
+	require.Equal(t, "c", presets[2].Name)
+
+	// presets are loaded by name with either extension
+	for _, preset := range presets {
+		loaded, err := m4b.LoadPreset(preset.Name, t.TempDir())
+		require.NoError(t, err)
+		require.Equal(t, preset, *loaded)
+	}
+}
@@ -56,6 +56,7 @@ func NewRecursiveProjectsFromPath(path string) ([]*Project, error) {
 // Depending on the config it might be:
 // - a single Project
 // - multiple Projects (when config Multi is true)
+// - multiple Projects per directory (when config GroupBy is set)
 func NewProjectsFromPath(path string) ([]*Project, error) {
 	var fullpath string
 
@@ -70,8 +71,18 @@ func NewProjectsFromPath(path string) ([]*Project, error) {
 		return nil, fmt.Errorf("could not read config file %s: %w", fullpath, err)
 	}
 
+	return NewProjectsFromConfig(*config, filepath.Dir(fullpath))
+}
+
+// NewProjectsFromConfig creates the projects for a config that belongs to
+// baseDir, the directory the narr.yaml is (or would be) located in.
+// Presets are resolved relative to baseDir.
+func NewProjectsFromConfig(config ProjectConfig, baseDir string) ([]*Project, error) {
+	if err := config.expandPresets(baseDir); err != nil {
+		return nil, err
+	}
+
 	if config.Multi {
-		baseDir := filepath.Dir(fullpath)
 		projectDirEntries, err := os.ReadDir(baseDir)
 		if err != nil {
 			return nil, fmt.Errorf("could not get multi project directories: %w", err)
@@ -84,7 +95,7 @@ func NewProjectsFromPath(path string) ([]*Project, error) {
 				continue
 			}
 
-			projectConfig := *config
+			projectConfig := config
 			projectConfig.ProjectPath = filepath.Join(baseDir, dirEntry.Name())
 
 			project, err := NewProject(projectConfig)
@@ -92,20 +103,25 @@ func NewProjectsFromPath(path string) ([]*Project, error) {
 				return nil, fmt.Errorf("could not create project for path '%s': %w", dirEntry, err)
 			}
 
-			projects = append(projects, project)
+			split, err := project.Split()
+			if err != nil {
+				return nil, fmt.Errorf("could not split project '%s': %w", projectConfig.ProjectPath, err)
+			}
+
+			projects = append(projects, split...)
 		}
 
 		return projects, nil
 
 	} else {
-		config.ProjectPath = filepath.Dir(fullpath)
+		config.ProjectPath = baseDir
 
-		project, err := NewProject(*config)
+		project, err := NewProject(config)
 		if err != nil {
 			return nil, err
 		}
 
-		return []*Project{project}, nil
+		return project.Split()
 	}
 }
 
@@ -140,16 +156,61 @@ func NewProjectWithDeps(config ProjectConfig, deps ProjectDependencies) (*Projec
 // It validates the configuration before creating the project.
 // Returns an error if the configuration is invalid.
 func NewProject(config ProjectConfig) (*Project, error) {
+	deps := DefaultProjectDependencies()
+	deps.AudioFileProvider = config.Input.AudioFileProvider()
+	return NewProjectWithDeps(config, deps)
+}
+
+// DefaultProjectDependencies returns the dependencies backed by the file system and FFmpeg.
+func DefaultProjectDependencies() ProjectDependencies {
 	audioFileProvider := &utils.OSAudioFileProvider{}
 	audioProcessor := &FFmpegAudioProcessor{Command: &ExecCommand{}}
 	trackFactory := &FFmpegTrackFactory{AudioProcessor: audioProcessor}
 
-	deps := ProjectDependencies{
+	return ProjectDependencies{
 		AudioFileProvider: audioFileProvider,
 		AudioProcessor:    audioProcessor,
 		TrackFactory:      trackFactory,
 	}
-	return NewProjectWithDeps(config, deps)
+}
+
+// WithConfig returns a new Project for the given config that shares the
+// dependencies of p. Tracks that p has already loaded are reused with the new
+// rules, so the audio files are not probed again.
+func (p *Project) WithConfig(config ProjectConfig) (*Project, error) {
+	project, err := NewProjectWithDeps(config, p.deps)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tracks != nil {
+		tracks := make([]Track, 0, len(p.tracks))
+		for _, track := range p.tracks {
+			track.PathRules = config.PathRules
+			track.DiscDetection = config.DiscDetection
+			track.MetadataRules = config.MetadataRules
+			track.sidecarPrecedence = config.Sidecars.precedence()
+			track.metadataCache = nil
+			track.tagOrder = nil
+			tracks = append(tracks, track)
+		}
+
+		projectDir, err := config.FullAudioFilePath()
+		if err != nil {
+			return nil, err
+		}
+		if err = orderTracks(tracks, config.Ordering, projectDir); err != nil {
+			return nil, err
+		}
+		project.tracks = tracks
+
+		silence := config.Chapters.Silence
+		if silence.noise() == p.Config.Chapters.Silence.noise() && silence.minLength() == p.Config.Chapters.Silence.minLength() {
+			project.silences = p.silences
+		}
+	}
+
+	return project, nil
 }
 
 // audioFileProvider defines the interface for providing audio files from a directory.
@@ -168,6 +229,9 @@ type audioProcessor interface {
 	ReadTitleAndDuration(file string) (string, float64, error)
 	ReadMetadata(file string) (string, error)
 	ToM4A(files []string, outputPath string) ([]string, error)
+	Cut(file string, start float64, end float64, outputFile string) error
+	ReadChapters(file string) ([]ChapterTiming, error)
+	DetectSilence(file string, noise string, minLength float64) ([]Silence, error)
 }
 
 type trackFactory interface {
@@ -179,10 +243,16 @@ type trackFactory interface {
 // It contains configuration, providers for audio files and metadata, and an audio processor
 // for handling audio file conversions and manipulations.
 type Project struct {
-	Config  ProjectConfig
-	tracks  []Track
-	workDir string
-	deps    ProjectDependencies
+	Config      ProjectConfig
+	tracks      []Track
+	chapters    []*Chapter           // computed chapters before overrides
+	silences    map[string][]Silence // detected silences by file
+	sidecars    map[string]Sidecar
+	group       string
+	groupSuffix string     // appended to the filename if a sibling has the same one
+	siblings    []*Project // the other projects split off the same project
+	workDir     string
+	deps        ProjectDependencies
 }
 
 type ProjectDependencies struct {
@@ -207,6 +277,10 @@ func (p *Project) ConvertToM4B() (string, error) {
 		return "", fmt.Errorf("could not load audio files: %w", err)
 	}
 
+	if err = p.ValidateOrder(); err != nil {
+		return "", err
+	}
+
 	finalFilename, err := p.Filename()
 	if err != nil {
 		return "", fmt.Errorf("could not get filename: %w", err)
@@ -216,16 +290,18 @@ func (p *Project) ConvertToM4B() (string, error) {
 		fmt.Println("Skipping, as already completed")
 		return finalFilename, nil
 	}
-	files := make([]string, 0, len(tracks))
-
-	for _, track := range tracks {
-		files = append(files, track.File)
+	// running chapters before conversion to prevent long wait before error
+	var chapters string
+	if p.Config.HasChapters {
+		chapters, err = p.Chapters()
+		if err != nil {
+			return "", fmt.Errorf("could not get chapters: %w", err)
+		}
 	}
 
-	// running chapters before conversion to prevent long wait before error
-	chapters, err := p.Chapters()
+	files, err := p.inputFiles(tracks)
 	if err != nil {
-		return "", fmt.Errorf("could not get chapters: %w", err)
+		return "", err
 	}
 
 	m4aFiles := files
@@ -292,6 +368,34 @@ func (p *Project) ConvertToM4B() (string, error) {
 	return finalFilename, nil
 }
 
+// inputFiles returns the audio files of the tracks. Tracks read from a cue
+// sheet are cut out of their file into the work dir.
+func (p *Project) inputFiles(tracks []Track) ([]string, error) {
+	files := make([]string, 0, len(tracks))
+	cutDir := filepath.Join(p.workDir, "cut")
+
+	for i, track := range tracks {
+		start, end, isSegment := track.Segment()
+		if !isSegment {
+			files = append(files, track.File)
+			continue
+		}
+
+		if err := os.MkdirAll(cutDir, 0755); err != nil {
+			return nil, fmt.Errorf("could not create cut dir: %w", err)
+		}
+
+		name := fmt.Sprintf("%03d %s", i+1, filepath.Base(track.File))
+		outputFile := filepath.Join(cutDir, name)
+		if err := p.deps.AudioProcessor.Cut(track.File, start, end, outputFile); err != nil {
+			return nil, fmt.Errorf("could not cut track %d out of %s: %w", i+1, track.File, err)
+		}
+		files = append(files, outputFile)
+	}
+
+	return files, nil
+}
+
 // Cover returns the path to the cover image for the audiobook.
 // It first checks for a cover specified in the configuration, then attempts to
 // extract a cover from the first audio file if no configuration cover exists.
@@ -314,7 +418,9 @@ func (p *Project) Cover() (string, error) {
 }
 
 // Tracks returns a sorted list of all audio tracks in the project.
-// Tracks are sorted by disc number and track number, with filename as a fallback.
+// Files described by a cue sheet are split into one track per cue track.
+// Tracks are ordered according to Config.Ordering, by default by disc number
+// and track number, with filename as a fallback.
 // Results are cached after the first call.
 func (p *Project) Tracks() ([]Track, error) {
 	if p.tracks != nil {
@@ -336,125 +442,350 @@ func (p *Project) Tracks() ([]Track, error) {
 		return nil, err
 	}
 
-	slices.SortFunc(tracks, sortTracks)
+	tracks, err = expandCueSheets(tracks)
+	if err != nil {
+		return nil, err
+	}
+
+	sidecars, err := p.Sidecars()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tracks {
+		tracks[i].projectPath = fullpath
+		tracks[i].PathRules = p.Config.PathRules
+		tracks[i].DiscDetection = p.Config.DiscDetection
+		tracks[i].sidecars = sidecars
+		tracks[i].sidecarPrecedence = p.Config.Sidecars.precedence()
+	}
+
+	if err = orderTracks(tracks, p.Config.Ordering, fullpath); err != nil {
+		return nil, err
+	}
 	p.tracks = tracks
 	return tracks, nil
 }
 
-func sortTracks(a, b Track) int {
-	discI, discIExists := a.DiscNumber()
-	discJ, discJExists := b.DiscNumber()
-
-	if discIExists && discJExists && discI != discJ {
-		return cmp.Compare(discI, discJ)
+// ValidateOrder returns an error if the order of the tracks is ambiguous,
+// i.e. if two tracks have the same known disc and the same track number when
+// ordering by tags.
+func (p *Project) ValidateOrder() error {
+	tracks, err := p.Tracks()
+	if err != nil {
+		return fmt.Errorf("could not load audio files: %w", err)
 	}
 
-	trackNumberI, trackNumberIExists := a.TrackNumber()
-	trackNumberJ, trackNumberJExists := b.TrackNumber()
+	if p.Config.Ordering.By != "" && p.Config.Ordering.By != OrderByTags {
+		return nil
+	}
 
-	if trackNumberIExists && trackNumberJExists && trackNumberI != trackNumberJ {
-		return cmp.Compare(trackNumberI, trackNumberJ)
+	if err = duplicateTrackNumbers(tracks); err != nil {
+		return fmt.Errorf("ambiguous track order: %w", err)
 	}
+	return nil
+}
+
+// sortTracks orders by disc and track number, falling back to the filename.
+func sortTracks(collator utils.Collator) func(a, b Track) int {
+	return func(a, b Track) int {
+		discI, discIExists := a.DiscNumber()
+		discJ, discJExists := b.DiscNumber()
+
+		if discIExists && discJExists && discI != discJ {
+			return cmp.Compare(discI, discJ)
+		}
 
-	return utils.NaturalCompare(a.File, b.File)
+		trackNumberI, trackNumberIExists := a.TrackNumber()
+		trackNumberJ, trackNumberJExists := b.TrackNumber()
+
+		if trackNumberIExists && trackNumberJExists && trackNumberI != trackNumberJ {
+			return cmp.Compare(trackNumberI, trackNumberJ)
+		}
+
+		return collator.Compare(a.File, b.File)
+	}
 }
 
-// Chapters generates chapter markers for the audiobook based on the track metadata
-// and configured chapter rules. Returns the chapter markers in FFmpeg metadata format.
+// Chapters generates chapter markers for the audiobook based on the track metadata,
+// the configured chapter title source, chapter rules and overrides.
+// Returns the chapter markers in FFmpeg metadata format.
 func (p *Project) Chapters() (string, error) {
+	chapters, err := p.ChapterList()
+	if err != nil {
+		return "", err
+	}
+
+	markers := make([]string, 0, len(chapters))
+
+	for i, chapter := range chapters {
+		markers = append(markers, chapter.ChapterMarker(i))
+	}
+
+	markersFileContent := strings.Join(markers, "\n\n")
+
+	return markersFileContent, nil
+}
+
+// ChapterList returns the chapters of the audiobook. Consecutive tracks are
+// grouped into chapters according to Config.Chapters.Grouping, then the
+// overrides of Config.Chapters.Overrides are applied.
+func (p *Project) ChapterList() ([]*Chapter, error) {
+	chapters, err := p.computedChapters()
+	if err != nil {
+		return nil, err
+	}
+	return applyChapterOverrides(chapters, p.Config.Chapters.Overrides)
+}
+
+// computedChapters returns the chapters before overrides are applied.
+// Results are cached after the first call.
+func (p *Project) computedChapters() ([]*Chapter, error) {
+	if p.chapters != nil {
+		return p.chapters, nil
+	}
+
+	chapters, err := p.loadChapters()
+	if err != nil {
+		return nil, err
+	}
+
+	p.chapters = chapters
+	return chapters, nil
+}
+
+// loadChapters computes the chapters from the tracks according to
+// Config.Chapters.Source.
+func (p *Project) loadChapters() ([]*Chapter, error) {
 	tracks, err := p.Tracks()
 	if err != nil {
-		return "", fmt.Errorf("could not load audio files: %w", err)
+		return nil, fmt.Errorf("could not load audio files: %w", err)
 	}
 
-	chapters := make(map[string]*Chapter)
-	var chapterOrder []string
-	var previousChapter *Chapter
+	switch p.Config.Chapters.Source {
+	case ChapterSourceFile:
+		return p.chaptersFromFile(tracks)
+	case ChapterSourceSilence:
+		candidates, total, err := p.silenceBoundaries(tracks)
+		if err != nil {
+			return nil, err
+		}
+		boundaries := pickBoundaries(candidates, total, p.Config.Chapters.Silence.TargetLength)
+		return p.generatedChapters(tracks, boundaries, total)
+	case ChapterSourceInterval:
+		boundaries, total, err := p.intervalBoundaries(tracks)
+		if err != nil {
+			return nil, err
+		}
+		return p.generatedChapters(tracks, boundaries, total)
+	}
+
+	chapters, _, err := p.groupChapters(tracks)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.embedChapters(chapters, tracks)
+}
+
+// groupChapters groups consecutive tracks into chapters according to
+// Config.Chapters.Grouping. It also returns the number of the chapter
+// (starting at 1) each track belongs to.
+func (p *Project) groupChapters(tracks []Track) ([]*Chapter, []int, error) {
+	var chapters []*Chapter
+	var previousKey string
+	trackChapters := make([]int, 0, len(tracks))
 
 	for i, track := range tracks {
-		title, duration, err := track.TitleAndDuration()
+		_, duration, err := track.TitleAndDuration()
 		if err != nil {
-			return "", fmt.Errorf("could not read file data for file %s: %w", track.File, err)
+			return nil, nil, fmt.Errorf("could not read file data for file %s: %w", track.File, err)
 		}
-		chapterName := title
 
-		for _, rule := range p.Config.ChapterRules {
-			chapterName, err = rule.Apply(chapterName)
-			if err != nil {
-				return "", fmt.Errorf("chapter rule invalid: %w", err)
-			}
+		// the track continues the current chapter if its key matches
+		chapterName, _, err := p.chapterTitle(&track, max(len(chapters), 1))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		key, err := p.Config.Chapters.groupKey(&track, chapterName, i)
+		if err != nil {
+			return nil, nil, err
 		}
 
-		value, exists := chapters[chapterName]
 		newFile := File{Name: track.File, Duration: duration}
 
-		if exists {
-			value.addFile(newFile)
-		} else {
-			newChapter := Chapter{
-				title:           chapterName,
-				previousChapter: previousChapter,
-				files:           []File{newFile},
-				index:           i,
+		if len(chapters) > 0 && key == previousKey {
+			chapters[len(chapters)-1].addFile(newFile)
+			trackChapters = append(trackChapters, len(chapters))
+			continue
+		}
+
+		if len(chapters) > 0 && p.Config.Chapters.titleDependsOnIndex() {
+			chapterName, _, err = p.chapterTitle(&track, len(chapters)+1)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			key, err = p.Config.Chapters.groupKey(&track, chapterName, i)
+			if err != nil {
+				return nil, nil, err
 			}
-			chapterOrder = append(chapterOrder, chapterName)
-			chapters[chapterName] = &newChapter
-			previousChapter = &newChapter
 		}
+
+		var previousChapter *Chapter
+		if len(chapters) > 0 {
+			previousChapter = chapters[len(chapters)-1]
+		}
+
+		chapters = append(chapters, &Chapter{
+			title:           chapterName,
+			previousChapter: previousChapter,
+			files:           []File{newFile},
+			baseIndex:       len(chapters),
+		})
+		trackChapters = append(trackChapters, len(chapters))
+		previousKey = key
 	}
 
-	markers := make([]string, 0, len(chapters))
+	return chapters, trackChapters, nil
+}
 
-	for i, name := range chapterOrder {
-		chapter := chapters[name]
-		markers = append(markers, chapter.ChapterMarker(i))
+// chaptersFromFile reads the chapters from Config.Chapters.File instead of
+// building them from the tracks.
+func (p *Project) chaptersFromFile(tracks []Track) ([]*Chapter, error) {
+	file := p.Config.Chapters.File
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(p.Config.ProjectPath, file)
 	}
 
-	markersFileContent := strings.Join(markers, "\n\n")
+	imported, err := ReadChapterFile(file, p.Config.Chapters.Format)
+	if err != nil {
+		return nil, err
+	}
 
-	return markersFileContent, nil
+	duration := 0.0
+	for _, track := range tracks {
+		_, trackDuration, err := track.TitleAndDuration()
+		if err != nil {
+			return nil, fmt.Errorf("could not read file data for file %s: %w", track.File, err)
+		}
+		duration += trackDuration
+	}
+
+	chapters, err := importedChapters(imported, duration, file)
+	if err != nil {
+		return nil, fmt.Errorf("chapters of %s do not fit the audiobook: %w", file, err)
+	}
+	return chapters, nil
+}
+
+// chapterTitle returns the title of the track from the configured title
+// source with the chapter rules applied, along with a trace of all rules.
+// index is the number of the chapter the track belongs to, starting at 1.
+func (p *Project) chapterTitle(track *Track, index int) (string, []RuleTraceEntry, error) {
+	title, err := p.Config.Chapters.title(track, index)
+	if err != nil {
+		return "", nil, err
+	}
+	return p.applyChapterRules(track, title)
+}
+
+// applyChapterRules applies the chapter rules to title and returns the
+// resulting chapter name along with a trace of all rules.
+func (p *Project) applyChapterRules(track *Track, title string) (string, []RuleTraceEntry, error) {
+	trace := make([]RuleTraceEntry, 0, len(p.Config.ChapterRules))
+	chapterName := title
+
+	for i, rule := range p.Config.ChapterRules {
+		entry := RuleTraceEntry{Index: i, Rule: rule.String(), Before: chapterName}
+
+		if rule.When != nil {
+			tags, _, err := track.Metadata()
+			if err != nil {
+				return "", nil, err
+			}
+
+			holds, err := rule.When.Evaluate(track.ruleContext(tags))
+			if err != nil {
+				return "", nil, err
+			}
+			if !holds {
+				entry.Skipped = true
+				entry.After = chapterName
+				trace = append(trace, entry)
+				continue
+			}
+		}
+
+		entry.Matched, entry.Groups = regexGroups(rule.Regex, chapterName)
+
+		var err error
+		chapterName, err = rule.Apply(chapterName)
+		if err != nil {
+			return "", nil, fmt.Errorf("chapter rule invalid: %w", err)
+		}
+
+		entry.After = chapterName
+		trace = append(trace, entry)
+	}
+
+	return chapterName, trace, nil
 }
 
 // Metadata returns the audiobook metadata in FFmpeg metadata format.
-// The metadata is derived from the first track and processed according to the
-// configured metadata rules.
+// The metadata of all tracks is processed according to the configured metadata
+// rules and combined according to the configured aggregation.
 func (p *Project) Metadata() (string, error) {
 	tags, tagOrder, err := p.getUpdatedMetadata()
 	if err != nil {
 		return "", err
 	}
 
-	firstLine := ";FFMETADATA1"
+	// track and disc of the first track do not apply to the book
+	bookTags := slices.DeleteFunc(slices.Clone(tagOrder), func(tag string) bool {
+		return tag == "track" || tag == "disc"
+	})
 
-	lines := []string{firstLine}
+	return formatMetadata(tags, bookTags), nil
+}
 
-	for _, tag := range tagOrder {
-		switch tag {
-		case "track":
-			continue
-		case "disc":
-			continue
-		default:
-			lines = append(lines, tag+"="+tags[tag])
+// Filename returns the output file name for the project.
+// It takes artist and book title from the metadata as a basis. Projects split
+// off by groupBy that share artist and title get the group appended.
+func (p *Project) Filename() (string, error) {
+	filename, err := p.filename("")
+	if err != nil {
+		return "", err
+	}
+
+	// groups of the same book (e.g. discs) would be written to the same file.
+	// Siblings without a filename can not collide.
+	for _, sibling := range p.siblings {
+		if other, err := sibling.filename(""); err == nil && other == filename {
+			return p.filename(p.groupSuffix)
 		}
 	}
 
-	return strings.Join(lines, "\n"), nil
+	return filename, nil
 }
 
-// Filename returns the output file name for the project.
-// It takes artist and book title from the first file as a basis.
-func (p *Project) Filename() (string, error) {
+func (p *Project) filename(suffix string) (string, error) {
 	artist, album, err := p.ArtistAndBookTitle()
 	if err != nil {
 		return "", err
 	}
 
+	name := album
+	if suffix != "" {
+		name = album + " - " + suffix
+	}
+
 	filename := filepath.Join(
 		p.Config.OutputPath(),
 		utils.SanitizePathComponent(artist),
 		utils.SanitizePathComponent(album),
-		utils.SanitizePathComponent(album)+".m4b",
+		utils.SanitizePathComponent(name)+".m4b",
 	)
 
 	return filename, nil
@@ -501,12 +832,66 @@ func (p *Project) getUpdatedMetadata() (map[string]string, []string, error) {
 		return nil, nil, errors.New("no audio files found")
 	}
 
-	metadata, tagOrder, err := tracks[0].Metadata()
+	return aggregateMetadata(tracks, p.Config.Aggregation)
+}
+
+// MetadataConflicts returns the tags (album and artist) on which the tracks
+// disagree. Differing values usually mean that two books are mixed in one folder.
+func (p *Project) MetadataConflicts() ([]MetadataConflict, error) {
+	tracks, err := p.Tracks()
+	if err != nil {
+		return nil, fmt.Errorf("could not load audio files: %w", err)
+	}
+
+	return metadataConflicts(tracks)
+}
+
+// Sidecars returns the sidecar files (OPF, metadata.json, desc.txt,
+// reader.txt) found in the project directory, keyed by source.
+// Results are cached after the first call.
+func (p *Project) Sidecars() (map[string]Sidecar, error) {
+	if p.sidecars != nil {
+		return p.sidecars, nil
+	}
+
+	fullpath, err := p.Config.FullAudioFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	sidecars, err := ReadSidecars(fullpath)
+	if errors.Is(err, os.ErrNotExist) {
+		sidecars = map[string]Sidecar{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	p.sidecars = sidecars
+	return sidecars, nil
+}
+
+// SidecarsByPrecedence returns the sidecars of the project ordered by
+// Config.Sidecars, the first one wins. The tags of the audio files are
+// included as a Sidecar without File to show their rank. Sidecars of sources
+// that are not listed are left out. Without sidecars, it returns nil.
+func (p *Project) SidecarsByPrecedence() ([]Sidecar, error) {
+	sidecars, err := p.Sidecars()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	if len(sidecars) == 0 {
+		return nil, nil
 	}
 
-	return metadata, tagOrder, nil
+	var ordered []Sidecar
+	for _, source := range p.Config.Sidecars.precedence() {
+		if source == SourceTags {
+			ordered = append(ordered, Sidecar{Source: SourceTags})
+		} else if sidecar, exists := sidecars[source]; exists {
+			ordered = append(ordered, sidecar)
+		}
+	}
+	return ordered, nil
 }
 
 func (p *Project) m4aPath() (string, error) {
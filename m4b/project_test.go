@@ -1,6 +1,7 @@
 package m4b_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -125,6 +126,31 @@ date=2002-09-16`,
 
 }
 
+func TestWithConfig(t *testing.T) {
+	deps := setupDeps()
+	project, err := m4b.NewProjectWithDeps(m4b.ProjectConfig{}, *deps)
+	require.NoError(t, err)
+
+	_, err = project.Tracks()
+	require.NoError(t, err)
+
+	// tracks are reused, so the audio files are not read again
+	deps.AudioProcessor.(*m4b.NullAudioProcessor).ErrMeta = errors.New("probed again")
+
+	updated, err := project.WithConfig(m4b.ProjectConfig{
+		MetadataRules: []m4b.MetadataRule{{Type: "set", Tag: "album", Value: "Another Book"}},
+	})
+	require.NoError(t, err)
+
+	_, album, err := updated.ArtistAndBookTitle()
+	require.NoError(t, err)
+	require.Equal(t, "Another Book", album)
+
+	_, album, err = project.ArtistAndBookTitle()
+	require.NoError(t, err)
+	require.Equal(t, "The Book?", album)
+}
+
 func setupDeps() *m4b.ProjectDependencies {
 	data := make(map[string]m4b.FileData)
 
@@ -189,3 +215,20 @@ func (f *FakeAudioFileProvider) AudioFiles(fullPath string) ([]string, error) {
 	}
 	return f.Files, nil
 }
+
+func TestInputConfig_Validate(t *testing.T) {
+	config := m4b.ProjectConfig{Input: m4b.InputConfig{Exclude: []string{"["}}}
+	require.ErrorContains(t, config.Validate(), "input invalid: invalid glob '['")
+
+	config = m4b.ProjectConfig{Input: m4b.InputConfig{MaxDepth: -1}}
+	require.ErrorContains(t, config.Validate(), "maxDepth must not be negative")
+
+	config = m4b.ProjectConfig{Input: m4b.InputConfig{Extensions: []string{"MP3", "wav"}}}
+	require.ErrorContains(t, config.Validate(), "input invalid: wav files can not be concatenated as they are, set shouldConvert: true")
+
+	config.ShouldConvert = true
+	require.NoError(t, config.Validate())
+
+	config = m4b.ProjectConfig{Input: m4b.InputConfig{Extensions: []string{".M4B", "flac"}}}
+	require.NoError(t, config.Validate())
+}
@@ -4,22 +4,71 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+	"text/template"
 
 	"github.com/achwo/narr/utils"
 )
 
 // MetadataRule defines a rule for modifying metadata tags in an M4B file.
-// Rules can be of different types like "regex", "delete", or "set" and operate
-// on specific metadata tags.
+// Rules operate on specific metadata tags. Supported types are:
+//   - set: sets tag to value
+//   - default: sets tag to value, if it is missing or empty
+//   - delete: removes tag
+//   - regex: replaces tag by format, filled with the capture groups of regex
+//   - replace: replaces all matches of regex in tag by replacement ($1 and $name expand to groups)
+//   - copy: copies the value of tag from to tag
+//   - move: like copy, but removes tag from afterwards
+//   - template: sets tag to the Go template over all tags, e.g. {{.artist}} liest {{.album}}
+//   - case: changes the case of tag to title, upper or lower
+//   - trim: trims whitespace (or the characters in value) from both ends of tag
+//   - pad: zero-pads the first number in tag to width digits
+//
+// With when, the rule is only applied to tracks for which the condition holds.
 type MetadataRule struct {
-	Type   string `yaml:"type"`
-	Tag    string `yaml:"tag,omitempty"`
-	Value  string `yaml:"value,omitempty"`
-	Regex  string `yaml:"regex,omitempty"`
-	Format string `yaml:"format,omitempty"`
+	Type        string     `yaml:"type"`
+	Tag         string     `yaml:"tag,omitempty"`
+	From        string     `yaml:"from,omitempty"`
+	Value       string     `yaml:"value,omitempty"`
+	Regex       string     `yaml:"regex,omitempty"`
+	Format      string     `yaml:"format,omitempty"`
+	Replacement string     `yaml:"replacement,omitempty"`
+	Template    string     `yaml:"template,omitempty"`
+	Case        string     `yaml:"case,omitempty"`
+	Width       int        `yaml:"width,omitempty"`
+	When        *Condition `yaml:"when,omitempty"`
 }
 
+// MetadataRuleTypes are the supported rule types, in the order documented on
+// MetadataRule.
+var MetadataRuleTypes = []string{
+	"set", "default", "delete", "regex", "replace", "copy", "move", "template", "case", "trim", "pad",
+}
+
+// metadataRuleFields are the fields each rule type takes besides tag and when.
+var metadataRuleFields = map[string][]string{
+	"set":      {"value"},
+	"default":  {"value"},
+	"delete":   {},
+	"regex":    {"regex", "format"},
+	"replace":  {"regex", "replacement"},
+	"copy":     {"from"},
+	"move":     {"from"},
+	"template": {"template"},
+	"case":     {"case"},
+	"trim":     {"value"},
+	"pad":      {"width"},
+}
+
+// MetadataRuleFields returns the fields the rule type takes besides tag and
+// when, e.g. regex and format for regex rules.
+func MetadataRuleFields(ruleType string) []string {
+	return metadataRuleFields[ruleType]
+}
+
+var firstNumber = regexp.MustCompile(`\d+`)
+
 // Apply executes the rule on the provided tags map, modifying the tags according
 // to the rule's type and parameters. Returns an error if the rule application fails.
 func (r *MetadataRule) Apply(tags map[string]string) error {
@@ -27,29 +76,92 @@ func (r *MetadataRule) Apply(tags map[string]string) error {
 	tagName := strings.ToLower(r.Tag)
 	value, exists := tags[tagName]
 
-	// For "set" and "delete" types, we don't require the tag to exist
-	if !exists && r.Type != "set" && r.Type != "delete" {
-		return fmt.Errorf("tag %s does not exist", tagName)
-	}
-
 	switch r.Type {
 	case "set":
 		// Set the value regardless of whether the tag exists
 		tags[tagName] = r.Value
+		return nil
+	case "default":
+		if value == "" {
+			tags[tagName] = r.Value
+		}
+		return nil
 	case "delete":
 		delete(tags, tagName)
+		return nil
+	case "copy", "move":
+		from := strings.ToLower(r.From)
+		fromValue, fromExists := tags[from]
+		if !fromExists {
+			return fmt.Errorf("tag %s does not exist", from)
+		}
+		if r.Type == "move" {
+			delete(tags, from)
+		}
+		tags[tagName] = fromValue
+		return nil
+	case "template":
+		tmpl, err := r.template()
+		if err != nil {
+			return err
+		}
+		var sb strings.Builder
+		if err = tmpl.Execute(&sb, tags); err != nil {
+			return fmt.Errorf("could not execute template '%s': %w", r.Template, err)
+		}
+		tags[tagName] = sb.String()
+		return nil
+	}
+
+	// all remaining types modify the existing value
+	if !exists {
+		return fmt.Errorf("tag %s does not exist", tagName)
+	}
+
+	switch r.Type {
 	case "regex":
 		regex, err := regexp.Compile(r.Regex)
 		if err != nil {
-			// TODO: might be better in construction (want to know validity in config check also)
 			return fmt.Errorf("metadata rule regex '%s' is invalid: %w", r.Regex, err)
 		}
 		newValue, err := utils.ApplyRegex(value, regex, r.Format)
 		if err != nil {
-			// TODO: might be better in construction (want to know validity in config check also)
 			return fmt.Errorf("could not apply rule '%s': %w", r.Regex, err)
 		}
 		tags[tagName] = newValue
+	case "replace":
+		regex, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return fmt.Errorf("metadata rule regex '%s' is invalid: %w", r.Regex, err)
+		}
+		tags[tagName] = regex.ReplaceAllString(value, r.Replacement)
+	case "case":
+		switch r.Case {
+		case "upper":
+			tags[tagName] = strings.ToUpper(value)
+		case "lower":
+			tags[tagName] = strings.ToLower(value)
+		case "title":
+			tags[tagName] = utils.TitleCase(value)
+		default:
+			return fmt.Errorf("unknown case: %s", r.Case)
+		}
+	case "trim":
+		if r.Value != "" {
+			tags[tagName] = strings.Trim(value, r.Value)
+		} else {
+			tags[tagName] = strings.TrimSpace(value)
+		}
+	case "pad":
+		loc := firstNumber.FindStringIndex(value)
+		if loc == nil {
+			return nil
+		}
+		number := value[loc[0]:loc[1]]
+		if missing := r.Width - len(number); missing > 0 {
+			number = strings.Repeat("0", missing) + number
+		}
+		tags[tagName] = value[:loc[0]] + number + value[loc[1]:]
 	default:
 		return errors.ErrUnsupported
 	}
@@ -63,11 +175,17 @@ func (r *MetadataRule) Validate() error {
 	if r.Tag == "" {
 		return errors.New("rule must have a tag")
 	}
+	if r.When != nil {
+		if err := r.When.Validate(); err != nil {
+			return fmt.Errorf("when invalid: %w", err)
+		}
+	}
 	switch r.Type {
 	case "delete":
 		if r.Value != "" || r.Regex != "" || r.Format != "" {
 			return errors.New("delete rule cannot have value, regex, or format")
 		}
+		return r.allowOnly(metadataRuleFields[r.Type]...)
 	case "set":
 		if r.Value == "" {
 			return errors.New("set rule requires a value")
@@ -75,6 +193,12 @@ func (r *MetadataRule) Validate() error {
 		if r.Regex != "" || r.Format != "" {
 			return errors.New("set rule cannot have regex or format")
 		}
+		return r.allowOnly(metadataRuleFields[r.Type]...)
+	case "default":
+		if r.Value == "" {
+			return errors.New("default rule requires a value")
+		}
+		return r.allowOnly(metadataRuleFields[r.Type]...)
 	case "regex":
 		if r.Regex == "" {
 			return errors.New("regex rule requires both regex")
@@ -82,17 +206,123 @@ func (r *MetadataRule) Validate() error {
 		if r.Value != "" {
 			return errors.New("regex rule cannot have value")
 		}
+		if _, err := regexp.Compile(r.Regex); err != nil {
+			return fmt.Errorf("regex '%s' is invalid: %w", r.Regex, err)
+		}
+		return r.allowOnly(metadataRuleFields[r.Type]...)
+	case "replace":
+		if r.Regex == "" {
+			return errors.New("replace rule requires a regex")
+		}
+		if _, err := regexp.Compile(r.Regex); err != nil {
+			return fmt.Errorf("regex '%s' is invalid: %w", r.Regex, err)
+		}
+		return r.allowOnly(metadataRuleFields[r.Type]...)
+	case "copy", "move":
+		if r.From == "" {
+			return fmt.Errorf("%s rule requires from", r.Type)
+		}
+		if strings.EqualFold(r.From, r.Tag) {
+			return fmt.Errorf("%s rule requires from to differ from tag", r.Type)
+		}
+		return r.allowOnly(metadataRuleFields[r.Type]...)
+	case "template":
+		if r.Template == "" {
+			return errors.New("template rule requires a template")
+		}
+		if _, err := r.template(); err != nil {
+			return err
+		}
+		return r.allowOnly(metadataRuleFields[r.Type]...)
+	case "case":
+		if !slices.Contains([]string{"title", "upper", "lower"}, r.Case) {
+			return fmt.Errorf("case rule requires case to be title, upper or lower, got '%s'", r.Case)
+		}
+		return r.allowOnly(metadataRuleFields[r.Type]...)
+	case "trim":
+		return r.allowOnly(metadataRuleFields[r.Type]...)
+	case "pad":
+		if r.Width <= 0 {
+			return errors.New("pad rule requires a positive width")
+		}
+		return r.allowOnly(metadataRuleFields[r.Type]...)
 	default:
 		return fmt.Errorf("unknown rule type: %s", r.Type)
 	}
+}
+
+// allowOnly returns an error if the rule has fields set besides tag, type and
+// the given ones.
+func (r *MetadataRule) allowOnly(allowed ...string) error {
+	fields := []struct {
+		name string
+		set  bool
+	}{
+		{"from", r.From != ""},
+		{"value", r.Value != ""},
+		{"regex", r.Regex != ""},
+		{"format", r.Format != ""},
+		{"replacement", r.Replacement != ""},
+		{"template", r.Template != ""},
+		{"case", r.Case != ""},
+		{"width", r.Width != 0},
+	}
+
+	for _, field := range fields {
+		if field.set && !slices.Contains(allowed, field.name) {
+			return fmt.Errorf("%s rule cannot have %s", r.Type, field.name)
+		}
+	}
+
 	return nil
 }
 
+// String describes the rule by its type and all fields that are set.
+func (r MetadataRule) String() string {
+	parts := []string{r.Type, r.Tag}
+
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"from", r.From},
+		{"value", r.Value},
+		{"regex", r.Regex},
+		{"format", r.Format},
+		{"replacement", r.Replacement},
+		{"template", r.Template},
+		{"case", r.Case},
+	}
+	for _, field := range fields {
+		if field.value != "" {
+			parts = append(parts, fmt.Sprintf("%s=%q", field.name, field.value))
+		}
+	}
+	if r.Width != 0 {
+		parts = append(parts, fmt.Sprintf("width=%d", r.Width))
+	}
+	if r.When != nil {
+		parts = append(parts, "(conditional)")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (r *MetadataRule) template() (*template.Template, error) {
+	tmpl, err := template.New(r.Tag).Option("missingkey=zero").Parse(r.Template)
+	if err != nil {
+		return nil, fmt.Errorf("template '%s' is invalid: %w", r.Template, err)
+	}
+	return tmpl, nil
+}
+
 // ChapterRule defines a rule for modifying chapter titles in an M4B file
-// using regex pattern matching and formatting.
+// using regex pattern matching and formatting. With when, the rule is only
+// applied to tracks for which the condition holds.
 type ChapterRule struct {
-	Regex  string `yaml:"regex"`
-	Format string `yaml:"format"`
+	Regex  string     `yaml:"regex"`
+	Format string     `yaml:"format"`
+	When   *Condition `yaml:"when,omitempty"`
 }
 
 // Validate checks if the chapter rule has both required regex and format fields.
@@ -101,6 +331,14 @@ func (r *ChapterRule) Validate() error {
 	if r.Regex == "" || r.Format == "" {
 		return errors.New("regex rule requires both regex and format")
 	}
+	if _, err := regexp.Compile(r.Regex); err != nil {
+		return fmt.Errorf("regex '%s' is invalid: %w", r.Regex, err)
+	}
+	if r.When != nil {
+		if err := r.When.Validate(); err != nil {
+			return fmt.Errorf("when invalid: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -109,8 +347,16 @@ func (r *ChapterRule) Validate() error {
 func (r *ChapterRule) Apply(chapter string) (string, error) {
 	regex, err := regexp.Compile(r.Regex)
 	if err != nil {
-		// TODO: might be better in construction (want to know validity in config check also)
 		return "", fmt.Errorf("Chapter rule regex '%s' is invalid: %w", r.Regex, err)
 	}
 	return utils.ApplyRegex(chapter, regex, r.Format)
 }
+
+// String describes the rule by its regex and format.
+func (r ChapterRule) String() string {
+	s := fmt.Sprintf("regex=%q format=%q", r.Regex, r.Format)
+	if r.When != nil {
+		s += " (conditional)"
+	}
+	return s
+}
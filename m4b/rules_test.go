@@ -218,3 +218,218 @@ album=Arkham Horror - Litanei der Träume`
 
 	assert.Equal(t, expectedMetadata, metadata)
 }
+
+func TestMetadataRule_Apply_Types(t *testing.T) {
+	tests := []struct {
+		name         string
+		rule         m4b.MetadataRule
+		initialTags  map[string]string
+		expectedTags map[string]string
+	}{
+		{
+			name:         "copy",
+			rule:         m4b.MetadataRule{Type: "copy", Tag: "album_artist", From: "artist"},
+			initialTags:  map[string]string{"artist": "Hans Wurst"},
+			expectedTags: map[string]string{"artist": "Hans Wurst", "album_artist": "Hans Wurst"},
+		},
+		{
+			name:         "move",
+			rule:         m4b.MetadataRule{Type: "move", Tag: "composer", From: "comment"},
+			initialTags:  map[string]string{"comment": "Reader", "artist": "Author"},
+			expectedTags: map[string]string{"composer": "Reader", "artist": "Author"},
+		},
+		{
+			name:         "template",
+			rule:         m4b.MetadataRule{Type: "template", Tag: "description", Template: "{{.artist}} liest {{.album}}{{.missing}}"},
+			initialTags:  map[string]string{"artist": "Rufus Beck", "album": "Harry Potter"},
+			expectedTags: map[string]string{"artist": "Rufus Beck", "album": "Harry Potter", "description": "Rufus Beck liest Harry Potter"},
+		},
+		{
+			name:         "replace with named groups",
+			rule:         m4b.MetadataRule{Type: "replace", Tag: "album", Regex: `Folge (?P<nr>\d+): (?P<title>.+)`, Replacement: "${nr}. $title"},
+			initialTags:  map[string]string{"album": "Folge 12: Der Fluch"},
+			expectedTags: map[string]string{"album": "12. Der Fluch"},
+		},
+		{
+			name:         "replace removes matches",
+			rule:         m4b.MetadataRule{Type: "replace", Tag: "title", Regex: ` \(CD \d\)`},
+			initialTags:  map[string]string{"title": "Kapitel 1 (CD 1)"},
+			expectedTags: map[string]string{"title": "Kapitel 1"},
+		},
+		{
+			name:         "case title",
+			rule:         m4b.MetadataRule{Type: "case", Tag: "album", Case: "title"},
+			initialTags:  map[string]string{"album": "DER HERR DER RINGE"},
+			expectedTags: map[string]string{"album": "Der Herr Der Ringe"},
+		},
+		{
+			name:         "case upper",
+			rule:         m4b.MetadataRule{Type: "case", Tag: "album", Case: "upper"},
+			initialTags:  map[string]string{"album": "Die Ärzte"},
+			expectedTags: map[string]string{"album": "DIE ÄRZTE"},
+		},
+		{
+			name:         "trim whitespace",
+			rule:         m4b.MetadataRule{Type: "trim", Tag: "album"},
+			initialTags:  map[string]string{"album": "  Album \t"},
+			expectedTags: map[string]string{"album": "Album"},
+		},
+		{
+			name:         "trim characters",
+			rule:         m4b.MetadataRule{Type: "trim", Tag: "album", Value: "_-"},
+			initialTags:  map[string]string{"album": "__Album-"},
+			expectedTags: map[string]string{"album": "Album"},
+		},
+		{
+			name:         "pad",
+			rule:         m4b.MetadataRule{Type: "pad", Tag: "album", Width: 3},
+			initialTags:  map[string]string{"album": "Folge 5: Teil 2"},
+			expectedTags: map[string]string{"album": "Folge 005: Teil 2"},
+		},
+		{
+			name:         "pad keeps longer numbers",
+			rule:         m4b.MetadataRule{Type: "pad", Tag: "album", Width: 2},
+			initialTags:  map[string]string{"album": "Folge 123"},
+			expectedTags: map[string]string{"album": "Folge 123"},
+		},
+		{
+			name:         "default sets missing tag",
+			rule:         m4b.MetadataRule{Type: "default", Tag: "genre", Value: "Hörbuch"},
+			initialTags:  map[string]string{"album": "Album"},
+			expectedTags: map[string]string{"album": "Album", "genre": "Hörbuch"},
+		},
+		{
+			name:         "default keeps existing tag",
+			rule:         m4b.MetadataRule{Type: "default", Tag: "genre", Value: "Hörbuch"},
+			initialTags:  map[string]string{"genre": "Hörspiel"},
+			expectedTags: map[string]string{"genre": "Hörspiel"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.rule.Validate())
+			require.NoError(t, tt.rule.Apply(tt.initialTags))
+			assert.Equal(t, tt.expectedTags, tt.initialTags)
+		})
+	}
+}
+
+func TestMetadataRule_Apply_MissingTag(t *testing.T) {
+	rules := []m4b.MetadataRule{
+		{Type: "copy", Tag: "album_artist", From: "artist"},
+		{Type: "pad", Tag: "album", Width: 3},
+		{Type: "case", Tag: "album", Case: "lower"},
+	}
+
+	for _, rule := range rules {
+		err := rule.Apply(map[string]string{})
+		require.ErrorContains(t, err, "does not exist")
+	}
+}
+
+func TestMetadataRule_Validate_Types(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   m4b.MetadataRule
+		errMsg string
+	}{
+		{name: "copy without from", rule: m4b.MetadataRule{Type: "copy", Tag: "a"}, errMsg: "copy rule requires from"},
+		{name: "move to itself", rule: m4b.MetadataRule{Type: "move", Tag: "a", From: "A"}, errMsg: "move rule requires from to differ from tag"},
+		{name: "copy with value", rule: m4b.MetadataRule{Type: "copy", Tag: "a", From: "b", Value: "c"}, errMsg: "copy rule cannot have value"},
+		{name: "template invalid", rule: m4b.MetadataRule{Type: "template", Tag: "a", Template: "{{.b"}, errMsg: "template '{{.b' is invalid"},
+		{name: "template missing", rule: m4b.MetadataRule{Type: "template", Tag: "a"}, errMsg: "template rule requires a template"},
+		{name: "replace invalid regex", rule: m4b.MetadataRule{Type: "replace", Tag: "a", Regex: "("}, errMsg: "regex '(' is invalid"},
+		{name: "replace with format", rule: m4b.MetadataRule{Type: "replace", Tag: "a", Regex: "a", Format: "%s"}, errMsg: "replace rule cannot have format"},
+		{name: "regex invalid", rule: m4b.MetadataRule{Type: "regex", Tag: "a", Regex: "(", Format: "%s"}, errMsg: "regex '(' is invalid"},
+		{name: "case unknown", rule: m4b.MetadataRule{Type: "case", Tag: "a", Case: "snake"}, errMsg: "case rule requires case to be title, upper or lower"},
+		{name: "pad without width", rule: m4b.MetadataRule{Type: "pad", Tag: "a"}, errMsg: "pad rule requires a positive width"},
+		{name: "default without value", rule: m4b.MetadataRule{Type: "default", Tag: "a"}, errMsg: "default rule requires a value"},
+		{name: "trim with width", rule: m4b.MetadataRule{Type: "trim", Tag: "a", Width: 2}, errMsg: "trim rule cannot have width"},
+		{name: "unknown type", rule: m4b.MetadataRule{Type: "upper", Tag: "a"}, errMsg: "unknown rule type: upper"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestMetadataRule_Integration_MoveRule(t *testing.T) {
+	data := map[string]m4b.FileData{
+		"file1.m4a": {
+			Title:    "Chapter 1",
+			Duration: 5000,
+			Metadata: `;FFMETADATA1
+title=Chapter 01
+artist=Hans Wurst
+comment=George Washington
+album=The Book`,
+		},
+	}
+
+	config := m4b.ProjectConfig{
+		MetadataRules: []m4b.MetadataRule{
+			{Type: "move", Tag: "composer", From: "comment"},
+		},
+	}
+
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, []string{"file1.m4a"}))
+	require.NoError(t, err)
+
+	metadata, err := project.Metadata()
+	require.NoError(t, err)
+
+	assert.Equal(t, `;FFMETADATA1
+title=Chapter 01
+artist=Hans Wurst
+album=The Book
+composer=George Washington`, metadata)
+}
+
+func TestMetadataRule_Integration_Escaping(t *testing.T) {
+	data := map[string]m4b.FileData{
+		"file1.m4a": {
+			Title:    "Chapter 1",
+			Duration: 5000,
+			Metadata: ";FFMETADATA1\ntitle=Eins\\; zwei\ncomment=erste Zeile\\\nzweite Zeile\nartist=Hans Wurst",
+		},
+	}
+
+	config := m4b.ProjectConfig{
+		MetadataRules: []m4b.MetadataRule{
+			{Type: "set", Tag: "album", Value: "Teil 1; Band #2"},
+			{Type: "template", Tag: "genre", Template: "{{.title}} = Hörspiel"},
+			{Type: "replace", Tag: "artist", Regex: "Wurst", Replacement: `Wurst\Käse`},
+		},
+	}
+
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, []string{"file1.m4a"}))
+	require.NoError(t, err)
+
+	tracks, err := project.Tracks()
+	require.NoError(t, err)
+	tags, _, err := tracks[0].Metadata()
+	require.NoError(t, err)
+	require.Equal(t, "Eins; zwei", tags["title"])
+	require.Equal(t, "erste Zeile\nzweite Zeile", tags["comment"])
+	require.Equal(t, "Eins; zwei = Hörspiel", tags["genre"])
+
+	metadata, err := project.Metadata()
+	require.NoError(t, err)
+	require.Equal(t, `;FFMETADATA1
+title=Eins\; zwei
+comment=erste Zeile\
+zweite Zeile
+artist=Hans Wurst\\Käse
+album=Teil 1\; Band \#2
+genre=Eins\; zwei \= Hörspiel`, metadata)
+
+	artist, album, err := project.ArtistAndBookTitle()
+	require.NoError(t, err)
+	require.Equal(t, `Hans Wurst\Käse`, artist)
+	require.Equal(t, "Teil 1; Band #2", album)
+}
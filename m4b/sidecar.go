@@ -0,0 +1,369 @@
+package m4b
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/achwo/narr/utils"
+)
+
+// Metadata sources, used in SidecarConfig.Precedence
+const (
+	SourceTags         = "tags"
+	SourceMetadataJSON = "metadata.json"
+	SourceOPF          = "opf"
+	SourceDescTxt      = "desc.txt"
+	SourceReaderTxt    = "reader.txt"
+)
+
+var defaultSidecarPrecedence = []string{SourceTags, SourceMetadataJSON, SourceOPF, SourceDescTxt, SourceReaderTxt}
+
+var htmlTag = regexp.MustCompile(`<[^>]+>`)
+
+// SidecarConfig configures the import of book metadata from sidecar files in
+// the project folder: an OPF file, an Audiobookshelf metadata.json, desc.txt
+// (description) and reader.txt (narrator).
+type SidecarConfig struct {
+	// Precedence lists the metadata sources from highest to lowest priority.
+	// "tags" stands for the tags of the audio files. Sources that are not
+	// listed are ignored, so an empty list (e.g. [tags]) disables sidecars.
+	// Defaults to tags, metadata.json, opf, desc.txt, reader.txt.
+	Precedence []string `yaml:"precedence,omitempty"`
+}
+
+// Validate checks that all sources in the precedence are known.
+func (c *SidecarConfig) Validate() error {
+	for _, source := range c.Precedence {
+		if !slices.Contains(defaultSidecarPrecedence, source) {
+			return fmt.Errorf("unknown metadata source: %s", source)
+		}
+	}
+	return nil
+}
+
+func (c *SidecarConfig) precedence() []string {
+	if len(c.Precedence) == 0 {
+		return defaultSidecarPrecedence
+	}
+	return c.Precedence
+}
+
+// Sidecar holds the book metadata read from a sidecar file.
+type Sidecar struct {
+	Source string
+	File   string
+	Tags   []utils.TagWithValue
+}
+
+// bookInfo contains the fields the sidecar formats have in common.
+type bookInfo struct {
+	title       string
+	subtitle    string
+	authors     []string
+	narrators   []string
+	series      string
+	seriesPart  string
+	description string
+	publisher   string
+	isbn        string
+	language    string
+	date        string
+	genres      []string
+}
+
+// tags maps the book info to the tag names used in the output file. Narrator
+// and series go to composer, grouping and sort_album, which have mp4 atoms.
+// subtitle, publisher, isbn and language are written as metadata tags.
+func (b bookInfo) tags() []utils.TagWithValue {
+	candidates := []utils.TagWithValue{
+		{Tag: "album", Value: b.title},
+		{Tag: "subtitle", Value: b.subtitle},
+		{Tag: "artist", Value: strings.Join(b.authors, ", ")},
+		{Tag: "composer", Value: strings.Join(b.narrators, ", ")},
+		{Tag: "grouping", Value: b.grouping()},
+		{Tag: "sort_album", Value: b.sortAlbum()},
+		{Tag: "description", Value: b.description},
+		{Tag: "publisher", Value: b.publisher},
+		{Tag: "isbn", Value: b.isbn},
+		{Tag: "language", Value: b.language},
+		{Tag: "date", Value: b.date},
+		{Tag: "genre", Value: strings.Join(b.genres, ", ")},
+	}
+
+	var tags []utils.TagWithValue
+	for _, tag := range candidates {
+		if value := strings.TrimSpace(tag.Value); value != "" {
+			tags = append(tags, utils.TagWithValue{Tag: tag.Tag, Value: value})
+		}
+	}
+	return tags
+}
+
+// grouping describes the series as "Series #Part", written to the grouping
+// atom that players show as series.
+func (b bookInfo) grouping() string {
+	if b.series == "" || b.seriesPart == "" {
+		return b.series
+	}
+	return b.series + " #" + b.seriesPart
+}
+
+// sortAlbum sorts the books of a series by their part, e.g. "Series 2 - Title".
+func (b bookInfo) sortAlbum() string {
+	if b.series == "" || b.title == "" {
+		return ""
+	}
+	if b.seriesPart == "" {
+		return b.series + " - " + b.title
+	}
+	return b.series + " " + b.seriesPart + " - " + b.title
+}
+
+// ReadSidecars detects the supported sidecar files in dir and reads them.
+// The result is keyed by source.
+func ReadSidecars(dir string) (map[string]Sidecar, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read directory %s: %w", dir, err)
+	}
+
+	sidecars := map[string]Sidecar{}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := strings.ToLower(entry.Name())
+		file := filepath.Join(dir, entry.Name())
+
+		var source string
+		var info bookInfo
+
+		switch {
+		case name == "metadata.json":
+			source = SourceMetadataJSON
+			info, err = readMetadataJSON(file)
+		case filepath.Ext(name) == ".opf":
+			source = SourceOPF
+			info, err = readOPF(file)
+		case name == "desc.txt":
+			source = SourceDescTxt
+			var description string
+			description, err = readText(file)
+			info.description = description
+		case name == "reader.txt":
+			source = SourceReaderTxt
+			var reader string
+			reader, err = readText(file)
+			info.narrators = []string{reader}
+		default:
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("could not read sidecar %s: %w", file, err)
+		}
+
+		if _, exists := sidecars[source]; exists {
+			continue
+		}
+		sidecars[source] = Sidecar{Source: source, File: file, Tags: info.tags()}
+	}
+
+	return sidecars, nil
+}
+
+// mergeSidecars merges the tags of the sidecars into tags according to the
+// precedence.
+func mergeSidecars(
+	tags map[string]string,
+	tagOrder []string,
+	sidecars map[string]Sidecar,
+	precedence []string,
+) (map[string]string, []string) {
+	tagsRank := slices.Index(precedence, SourceTags)
+	if tagsRank < 0 {
+		tagsRank = len(precedence)
+	}
+
+	// rank of the source that provided the current value of a tag
+	ranks := map[string]int{}
+	for tag, value := range tags {
+		if value != "" {
+			ranks[tag] = tagsRank
+		}
+	}
+
+	for rank, source := range precedence {
+		sidecar, exists := sidecars[source]
+		if !exists {
+			continue
+		}
+
+		for _, tag := range sidecar.Tags {
+			if current, set := ranks[tag.Tag]; set && current <= rank {
+				continue
+			}
+
+			if _, exists := tags[tag.Tag]; !exists {
+				tagOrder = append(tagOrder, tag.Tag)
+			}
+			tags[tag.Tag] = tag.Value
+			ranks[tag.Tag] = rank
+		}
+	}
+
+	return tags, tagOrder
+}
+
+func readText(file string) (string, error) {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	text := strings.ReplaceAll(string(bytes), "\r\n", "\n")
+	return strings.TrimSpace(text), nil
+}
+
+// audiobookshelfMetadata is the metadata.json written by Audiobookshelf
+type audiobookshelfMetadata struct {
+	Title         string   `json:"title"`
+	Subtitle      string   `json:"subtitle"`
+	Authors       []string `json:"authors"`
+	Narrators     []string `json:"narrators"`
+	Series        []string `json:"series"`
+	Genres        []string `json:"genres"`
+	PublishedYear string   `json:"publishedYear"`
+	PublishedDate string   `json:"publishedDate"`
+	Publisher     string   `json:"publisher"`
+	Description   string   `json:"description"`
+	ISBN          string   `json:"isbn"`
+	Language      string   `json:"language"`
+}
+
+func readMetadataJSON(file string) (bookInfo, error) {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return bookInfo{}, err
+	}
+
+	var metadata audiobookshelfMetadata
+	if err = json.Unmarshal(bytes, &metadata); err != nil {
+		return bookInfo{}, err
+	}
+
+	info := bookInfo{
+		title:       metadata.Title,
+		subtitle:    metadata.Subtitle,
+		authors:     metadata.Authors,
+		narrators:   metadata.Narrators,
+		genres:      metadata.Genres,
+		publisher:   metadata.Publisher,
+		description: metadata.Description,
+		isbn:        metadata.ISBN,
+		language:    metadata.Language,
+		date:        metadata.PublishedYear,
+	}
+
+	if metadata.PublishedDate != "" {
+		info.date = metadata.PublishedDate
+	}
+
+	// series are stored as "Name #Part"
+	if len(metadata.Series) > 0 {
+		name, part, _ := strings.Cut(metadata.Series[0], " #")
+		info.series = name
+		info.seriesPart = part
+	}
+
+	return info, nil
+}
+
+type opfPackage struct {
+	Metadata struct {
+		Titles   []string `xml:"title"`
+		Creators []struct {
+			Role  string `xml:"role,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"creator"`
+		Description string   `xml:"description"`
+		Publisher   string   `xml:"publisher"`
+		Language    string   `xml:"language"`
+		Date        string   `xml:"date"`
+		Subjects    []string `xml:"subject"`
+		Identifiers []struct {
+			Scheme string `xml:"scheme,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"identifier"`
+		Metas []struct {
+			Name    string `xml:"name,attr"`
+			Content string `xml:"content,attr"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+}
+
+func readOPF(file string) (bookInfo, error) {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return bookInfo{}, err
+	}
+
+	var pkg opfPackage
+	if err = xml.Unmarshal(bytes, &pkg); err != nil {
+		return bookInfo{}, err
+	}
+
+	metadata := pkg.Metadata
+	if len(metadata.Titles) == 0 && len(metadata.Creators) == 0 {
+		return bookInfo{}, errors.New("no opf metadata found")
+	}
+
+	info := bookInfo{
+		description: htmlTag.ReplaceAllString(metadata.Description, ""),
+		publisher:   metadata.Publisher,
+		language:    metadata.Language,
+		date:        metadata.Date,
+		genres:      metadata.Subjects,
+	}
+
+	if len(metadata.Titles) > 0 {
+		info.title = metadata.Titles[0]
+	}
+
+	for _, creator := range metadata.Creators {
+		switch creator.Role {
+		case "nrt":
+			info.narrators = append(info.narrators, strings.TrimSpace(creator.Value))
+		case "", "aut":
+			info.authors = append(info.authors, strings.TrimSpace(creator.Value))
+		}
+	}
+
+	for _, identifier := range metadata.Identifiers {
+		value := strings.TrimSpace(identifier.Value)
+		if strings.EqualFold(identifier.Scheme, "isbn") {
+			info.isbn = value
+		} else if isbn, found := strings.CutPrefix(strings.ToLower(value), "urn:isbn:"); found {
+			info.isbn = isbn
+		}
+	}
+
+	for _, meta := range metadata.Metas {
+		switch meta.Name {
+		case "calibre:series":
+			info.series = meta.Content
+		case "calibre:series_index":
+			info.seriesPart = strings.TrimSuffix(meta.Content, ".0")
+		}
+	}
+
+	return info, nil
+}
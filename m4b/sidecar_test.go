@@ -0,0 +1,229 @@
+package m4b_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/achwo/narr/utils"
+	"github.com/stretchr/testify/require"
+)
+
+const testOPF = `<?xml version="1.0" encoding="utf-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+    <dc:title>Der Hobbit</dc:title>
+    <dc:creator opf:role="aut">J.R.R. Tolkien</dc:creator>
+    <dc:creator opf:role="nrt">Gert Heidenreich</dc:creator>
+    <dc:description>&lt;p&gt;Bilbo geht auf Reisen.&lt;/p&gt;</dc:description>
+    <dc:publisher>Der Hörverlag</dc:publisher>
+    <dc:language>de</dc:language>
+    <dc:identifier opf:scheme="ISBN">9783867174784</dc:identifier>
+    <meta name="calibre:series" content="Mittelerde"/>
+    <meta name="calibre:series_index" content="1.0"/>
+  </metadata>
+</package>`
+
+const testMetadataJSON = `{
+  "title": "Der Hobbit (ABS)",
+  "authors": ["J.R.R. Tolkien"],
+  "narrators": ["Rufus Beck"],
+  "series": ["Mittelerde #0"],
+  "genres": ["Fantasy"],
+  "publishedYear": "2012",
+  "isbn": "123"
+}`
+
+func TestReadSidecars(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "book.opf"), []byte(testOPF), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "metadata.json"), []byte(testMetadataJSON), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "desc.txt"), []byte("Line 1\r\nLine 2\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "reader.txt"), []byte("Andreas Fröhlich\n"), 0644))
+
+	sidecars, err := m4b.ReadSidecars(dir)
+	require.NoError(t, err)
+	require.Len(t, sidecars, 4)
+
+	require.Equal(t, []utils.TagWithValue{
+		{Tag: "album", Value: "Der Hobbit"},
+		{Tag: "artist", Value: "J.R.R. Tolkien"},
+		{Tag: "composer", Value: "Gert Heidenreich"},
+		{Tag: "grouping", Value: "Mittelerde #1"},
+		{Tag: "sort_album", Value: "Mittelerde 1 - Der Hobbit"},
+		{Tag: "description", Value: "Bilbo geht auf Reisen."},
+		{Tag: "publisher", Value: "Der Hörverlag"},
+		{Tag: "isbn", Value: "9783867174784"},
+		{Tag: "language", Value: "de"},
+	}, sidecars[m4b.SourceOPF].Tags)
+
+	require.Equal(t, []utils.TagWithValue{
+		{Tag: "album", Value: "Der Hobbit (ABS)"},
+		{Tag: "artist", Value: "J.R.R. Tolkien"},
+		{Tag: "composer", Value: "Rufus Beck"},
+		{Tag: "grouping", Value: "Mittelerde #0"},
+		{Tag: "sort_album", Value: "Mittelerde 0 - Der Hobbit (ABS)"},
+		{Tag: "isbn", Value: "123"},
+		{Tag: "date", Value: "2012"},
+		{Tag: "genre", Value: "Fantasy"},
+	}, sidecars[m4b.SourceMetadataJSON].Tags)
+
+	require.Equal(t, []utils.TagWithValue{{Tag: "description", Value: "Line 1\nLine 2"}}, sidecars[m4b.SourceDescTxt].Tags)
+	require.Equal(t, []utils.TagWithValue{{Tag: "composer", Value: "Andreas Fröhlich"}}, sidecars[m4b.SourceReaderTxt].Tags)
+}
+
+func TestSidecars_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "book.opf"), []byte(testOPF), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "desc.txt"), []byte("Line 1\nLine 2; with = signs"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "reader.txt"), []byte("Andreas Fröhlich"), 0644))
+
+	data := map[string]m4b.FileData{
+		"1.flac": {Title: "Kapitel 1", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Hobbit\nartist=Tolkien\ncomposer="},
+	}
+
+	tests := []struct {
+		name       string
+		precedence []string
+		expected   string
+	}{
+		{
+			name: "default keeps tags",
+			expected: `;FFMETADATA1
+album=Hobbit
+artist=Tolkien
+composer=Gert Heidenreich
+grouping=Mittelerde \#1
+sort_album=Mittelerde 1 - Der Hobbit
+description=Bilbo geht auf Reisen.
+publisher=Der Hörverlag
+isbn=9783867174784
+language=de`,
+		},
+		{
+			name:       "sidecars first",
+			precedence: []string{"desc.txt", "reader.txt", "tags"},
+			expected: `;FFMETADATA1
+album=Hobbit
+artist=Tolkien
+composer=Andreas Fröhlich
+description=Line 1\
+Line 2\; with \= signs`,
+		},
+		{
+			name:       "only tags",
+			precedence: []string{"tags"},
+			expected: `;FFMETADATA1
+album=Hobbit
+artist=Tolkien
+composer=`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := m4b.ProjectConfig{
+				ProjectPath: dir,
+				Sidecars:    m4b.SidecarConfig{Precedence: tt.precedence},
+			}
+			project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, []string{"1.flac"}))
+			require.NoError(t, err)
+
+			metadata, err := project.Metadata()
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, metadata)
+		})
+	}
+}
+
+func TestSidecars_ByPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "book.opf"), []byte(testOPF), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "metadata.json"), []byte(testMetadataJSON), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "desc.txt"), []byte("Beschreibung"), 0644))
+
+	sources := func(precedence []string) []string {
+		config := m4b.ProjectConfig{ProjectPath: dir, Sidecars: m4b.SidecarConfig{Precedence: precedence}}
+		project, err := m4b.NewProjectWithDeps(config, fakeDeps(nil, nil))
+		require.NoError(t, err)
+
+		sidecars, err := project.SidecarsByPrecedence()
+		require.NoError(t, err)
+
+		var sources []string
+		for _, sidecar := range sidecars {
+			sources = append(sources, sidecar.Source)
+		}
+		return sources
+	}
+
+	require.Equal(t, []string{"tags", "metadata.json", "opf", "desc.txt"}, sources(nil))
+	require.Equal(t, []string{"opf", "tags"}, sources([]string{"opf", "reader.txt", "tags"}))
+	require.Equal(t, []string{"tags"}, sources([]string{"tags"}))
+
+	empty := m4b.ProjectConfig{ProjectPath: t.TempDir()}
+	project, err := m4b.NewProjectWithDeps(empty, fakeDeps(nil, nil))
+	require.NoError(t, err)
+	sidecars, err := project.SidecarsByPrecedence()
+	require.NoError(t, err)
+	require.Nil(t, sidecars)
+}
+
+func TestSidecars_RulesApplyToSidecarValues(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "book.opf"), []byte(testOPF), 0644))
+
+	data := map[string]m4b.FileData{
+		"1.flac": {Title: "Kapitel 1", Duration: 10, Metadata: ";FFMETADATA1\ntitle=Kapitel 1"},
+	}
+	config := m4b.ProjectConfig{
+		ProjectPath: dir,
+		MetadataRules: []m4b.MetadataRule{
+			{Type: "regex", Tag: "composer", Regex: `^Gert (.*)$`, Format: "G. %s"},
+			{Type: "template", Tag: "artist", Template: "{{.artist}} = Autor"},
+		},
+	}
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, []string{"1.flac"}))
+	require.NoError(t, err)
+
+	metadata, err := project.Metadata()
+	require.NoError(t, err)
+	require.Contains(t, metadata, "composer=G. Heidenreich")
+	require.Contains(t, metadata, "artist=J.R.R. Tolkien \\= Autor")
+
+	// values are only escaped within the metadata file
+	artist, _, err := project.ArtistAndBookTitle()
+	require.NoError(t, err)
+	require.Equal(t, "J.R.R. Tolkien = Autor", artist)
+}
+
+func TestSidecars_UnescapedOutsideMetadata(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "desc.txt"), []byte("Eins; zwei"), 0644))
+
+	data := map[string]m4b.FileData{
+		"1.flac": {Title: "Kapitel 1", Duration: 10, Metadata: ";FFMETADATA1\nartist=Autor\nalbum=Teil 1\\; Der Anfang"},
+	}
+	project, err := m4b.NewProjectWithDeps(m4b.ProjectConfig{ProjectPath: dir}, fakeDeps(data, []string{"1.flac"}))
+	require.NoError(t, err)
+
+	metadata, err := project.Metadata()
+	require.NoError(t, err)
+	require.Contains(t, metadata, "album=Teil 1\\; Der Anfang")
+	require.Contains(t, metadata, "description=Eins\\; zwei")
+
+	_, album, err := project.ArtistAndBookTitle()
+	require.NoError(t, err)
+	require.Equal(t, "Teil 1; Der Anfang", album)
+
+	filename, err := project.Filename()
+	require.NoError(t, err)
+	require.Equal(t, "Teil 1_ Der Anfang.m4b", filepath.Base(filename))
+}
+
+func TestSidecars_InvalidSource(t *testing.T) {
+	config := m4b.ProjectConfig{Sidecars: m4b.SidecarConfig{Precedence: []string{"nfo"}}}
+	_, err := m4b.NewProjectWithDeps(config, fakeDeps(nil, nil))
+	require.ErrorContains(t, err, "unknown metadata source: nfo")
+}
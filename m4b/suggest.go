@@ -0,0 +1,265 @@
+package m4b
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// trackNumberPrefix matches titles like "01 - Title", "3. Title" or "12 Title".
+var trackNumberPrefix = regexp.MustCompile(`^\d+\s*[-._:)]?\s+\S`)
+
+const trackNumberPrefixRule = `^\d+\s*[-._:)]?\s+(.+)$`
+
+var coverFileNames = []string{"cover.jpg", "cover.jpeg", "cover.png", "folder.jpg", "folder.jpeg", "folder.png"}
+
+// ConfigSuggestion is a ProjectConfig inferred from the audio files of a
+// directory, together with the reasons for every suggested setting.
+type ConfigSuggestion struct {
+	Config ProjectConfig
+	// Reasons maps the yaml key of a suggested setting to a human readable explanation.
+	Reasons map[string]string
+	// TrackDir is the directory whose tracks were analyzed. In multi mode it is
+	// the first project directory.
+	TrackDir string
+}
+
+// SuggestConfig probes the audio files below path and proposes a ProjectConfig.
+// It suggests chapters when the titles vary, chapter rules that strip track
+// number prefixes and common suffixes, a local cover image and multi mode when
+// the subdirectories hold different albums.
+func SuggestConfig(path string, deps ProjectDependencies) (*ConfigSuggestion, error) {
+	suggestion := &ConfigSuggestion{
+		Config: ProjectConfig{
+			MetadataRules: []MetadataRule{},
+			ChapterRules:  []ChapterRule{},
+		},
+		Reasons:  map[string]string{},
+		TrackDir: path,
+	}
+
+	trackDir := path
+
+	albumDirs, err := suggestAlbumDirs(path, deps)
+	if err != nil {
+		return nil, err
+	}
+	if len(albumDirs) > 1 {
+		suggestion.Config.Multi = true
+		suggestion.Reasons["multi"] = fmt.Sprintf(
+			"suggested: %d subdirectories contain different albums, each becomes its own project",
+			len(albumDirs),
+		)
+		trackDir = albumDirs[0]
+		suggestion.TrackDir = trackDir
+	}
+
+	if cover, ok := findCover(trackDir); ok {
+		if suggestion.Config.Multi {
+			// multi projects share the config, so the cover has to be relative to each project
+			cover = filepath.Base(cover)
+		} else if rel, err := filepath.Rel(path, cover); err == nil {
+			cover = rel
+		}
+		suggestion.Config.CoverPath = cover
+		suggestion.Reasons["coverPath"] = "suggested: found a local cover image"
+	}
+
+	project, err := NewProjectWithDeps(ProjectConfig{ProjectPath: trackDir}, deps)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks, err := project.Tracks()
+	if err != nil {
+		return nil, fmt.Errorf("could not load tracks: %w", err)
+	}
+
+	titles := make([]string, 0, len(tracks))
+	for _, track := range tracks {
+		title, _, err := track.TitleAndDuration()
+		if err != nil {
+			return nil, fmt.Errorf("could not read title of %s: %w", track.File, err)
+		}
+		titles = append(titles, title)
+	}
+
+	distinct := distinctStrings(titles)
+	if len(distinct) < 2 {
+		return suggestion, nil
+	}
+
+	suggestion.Config.HasChapters = true
+	suggestion.Reasons["hasChapters"] = fmt.Sprintf(
+		"suggested: the tracks have %d different titles",
+		len(distinct),
+	)
+
+	var reasons []string
+
+	if allMatch(distinct, trackNumberPrefix) {
+		rule := ChapterRule{Regex: trackNumberPrefixRule, Format: "%s"}
+		suggestion.Config.ChapterRules = append(suggestion.Config.ChapterRules, rule)
+		reasons = append(reasons, "strip the track number prefix from the titles")
+
+		for i, title := range distinct {
+			distinct[i], _ = rule.Apply(title)
+		}
+	}
+
+	if suffix := commonSuffix(distinct); suffix != "" {
+		suggestion.Config.ChapterRules = append(suggestion.Config.ChapterRules, ChapterRule{
+			Regex:  "^(.+?)" + regexp.QuoteMeta(suffix) + "$",
+			Format: "%s",
+		})
+		reasons = append(reasons, fmt.Sprintf("strip the common suffix '%s'", suffix))
+	}
+
+	if len(reasons) > 0 {
+		suggestion.Reasons["chapterRules"] = "suggested: " + strings.Join(reasons, ", ")
+	}
+
+	return suggestion, nil
+}
+
+// YAML renders the suggested config with the reasons as comments above the
+// suggested settings.
+func (s *ConfigSuggestion) YAML() ([]byte, error) {
+	var doc yaml.Node
+	if err := doc.Encode(s.Config); err != nil {
+		return nil, fmt.Errorf("could not encode config: %w", err)
+	}
+
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key := doc.Content[i]
+		if reason, ok := s.Reasons[key.Value]; ok {
+			key.HeadComment = reason
+		}
+	}
+
+	return yaml.Marshal(&doc)
+}
+
+// suggestAlbumDirs returns the direct subdirectories of path holding audio files,
+// if they contain more than one distinct album. Otherwise it returns nil.
+func suggestAlbumDirs(path string, deps ProjectDependencies) ([]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read directory %s: %w", path, err)
+	}
+
+	var dirs []string
+	albums := map[string]bool{}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(path, entry.Name())
+		files, err := deps.AudioFileProvider.AudioFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		track, err := deps.TrackFactory.LoadTrack(files[0], nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not load track %s: %w", files[0], err)
+		}
+
+		album, _ := track.MetadataTag("album")
+		albums[album] = true
+		dirs = append(dirs, dir)
+	}
+
+	if len(albums) < 2 {
+		return nil, nil
+	}
+
+	return dirs, nil
+}
+
+func findCover(dir string) (string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if slices.Contains(coverFileNames, strings.ToLower(entry.Name())) {
+			return filepath.Join(dir, entry.Name()), true
+		}
+	}
+
+	return "", false
+}
+
+func distinctStrings(values []string) []string {
+	var distinct []string
+	for _, value := range values {
+		if !slices.Contains(distinct, value) {
+			distinct = append(distinct, value)
+		}
+	}
+	return distinct
+}
+
+func allMatch(values []string, regex *regexp.Regexp) bool {
+	for _, value := range values {
+		if !regex.MatchString(value) {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
+// commonSuffix returns the longest suffix shared by all values that starts at
+// a word boundary and leaves a non-empty remainder for every value.
+func commonSuffix(values []string) string {
+	if len(values) < 2 {
+		return ""
+	}
+
+	suffix := []rune(values[0])
+	for _, value := range values[1:] {
+		runes := []rune(value)
+		n := 0
+		for n < len(suffix) && n < len(runes) && suffix[len(suffix)-1-n] == runes[len(runes)-1-n] {
+			n++
+		}
+		suffix = suffix[len(suffix)-n:]
+	}
+
+	// only cut at word boundaries, e.g. " (gekürzt)" but not "el 1" of "Kapitel 1"
+	for len(suffix) > 0 && !isSuffixBoundary(suffix[0]) {
+		suffix = suffix[1:]
+	}
+
+	result := string(suffix)
+	if strings.TrimSpace(result) == "" {
+		return ""
+	}
+
+	for _, value := range values {
+		if strings.TrimSpace(strings.TrimSuffix(value, result)) == "" {
+			return ""
+		}
+	}
+
+	return result
+}
+
+func isSuffixBoundary(r rune) bool {
+	return strings.ContainsRune(" -_([", r)
+}
@@ -0,0 +1,102 @@
+package m4b_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestConfig_Chapters(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Cover.jpg"), []byte{}, 0644))
+
+	data := map[string]m4b.FileData{
+		"1.flac": {Title: "01 - Der Anfang (gekürzt)", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Book"},
+		"2.flac": {Title: "02 - Die Mitte (gekürzt)", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Book"},
+		"3.flac": {Title: "03 - Das Ende (gekürzt)", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Book"},
+	}
+	deps := fakeDeps(data, []string{"1.flac", "2.flac", "3.flac"})
+
+	suggestion, err := m4b.SuggestConfig(dir, deps)
+	require.NoError(t, err)
+
+	require.True(t, suggestion.Config.HasChapters)
+	require.False(t, suggestion.Config.Multi)
+	require.Equal(t, "Cover.jpg", suggestion.Config.CoverPath)
+	require.Len(t, suggestion.Config.ChapterRules, 2)
+
+	title := "01 - Der Anfang (gekürzt)"
+	for _, rule := range suggestion.Config.ChapterRules {
+		title, err = rule.Apply(title)
+		require.NoError(t, err)
+	}
+	require.Equal(t, "Der Anfang", title)
+
+	out, err := suggestion.YAML()
+	require.NoError(t, err)
+	require.Contains(t, string(out), "# suggested: the tracks have 3 different titles\nhasChapters: true")
+	require.Contains(t, string(out), "# suggested: found a local cover image\ncoverPath: Cover.jpg")
+	require.Contains(t, string(out), "strip the common suffix ' (gekürzt)'")
+}
+
+func TestSuggestConfig_SameTitles(t *testing.T) {
+	data := map[string]m4b.FileData{
+		"1.flac": {Title: "Book", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Book"},
+		"2.flac": {Title: "Book", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Book"},
+	}
+
+	suggestion, err := m4b.SuggestConfig(t.TempDir(), fakeDeps(data, []string{"1.flac", "2.flac"}))
+	require.NoError(t, err)
+
+	require.False(t, suggestion.Config.HasChapters)
+	require.Empty(t, suggestion.Config.ChapterRules)
+	require.Empty(t, suggestion.Reasons)
+}
+
+func TestSuggestConfig_Multi(t *testing.T) {
+	dir := t.TempDir()
+	one := filepath.Join(dir, "Folge 1")
+	two := filepath.Join(dir, "Folge 2")
+	require.NoError(t, os.Mkdir(one, 0755))
+	require.NoError(t, os.Mkdir(two, 0755))
+
+	data := map[string]m4b.FileData{
+		"1/1.flac": {Title: "Teil 1", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Folge 1"},
+		"2/1.flac": {Title: "Teil 1", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Folge 2"},
+	}
+	processor := &m4b.NullAudioProcessor{Data: data}
+	deps := m4b.ProjectDependencies{
+		AudioFileProvider: &dirAudioFileProvider{Files: map[string][]string{
+			one: {"1/1.flac"},
+			two: {"2/1.flac"},
+		}},
+		AudioProcessor: processor,
+		TrackFactory:   &m4b.FFmpegTrackFactory{AudioProcessor: processor},
+	}
+
+	suggestion, err := m4b.SuggestConfig(dir, deps)
+	require.NoError(t, err)
+	require.True(t, suggestion.Config.Multi)
+	require.Contains(t, suggestion.Reasons["multi"], "2 subdirectories")
+}
+
+func fakeDeps(data map[string]m4b.FileData, files []string) m4b.ProjectDependencies {
+	processor := &m4b.NullAudioProcessor{Data: data}
+	return m4b.ProjectDependencies{
+		AudioFileProvider: &FakeAudioFileProvider{Files: files},
+		AudioProcessor:    processor,
+		TrackFactory:      &m4b.FFmpegTrackFactory{AudioProcessor: processor},
+	}
+}
+
+// dirAudioFileProvider returns the configured files per directory
+type dirAudioFileProvider struct {
+	Files map[string][]string
+}
+
+func (f *dirAudioFileProvider) AudioFiles(fullPath string) ([]string, error) {
+	return f.Files[fullPath], nil
+}
@@ -0,0 +1,99 @@
+package m4b
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// RuleTraceEntry records what a single rule did to a track.
+type RuleTraceEntry struct {
+	Index   int    // Position of the rule in its rule list
+	Rule    string // Description of the rule
+	Tag     string // Tag the rule writes to, empty for chapter rules
+	Before  string
+	After   string
+	Skipped bool // The condition of the rule did not hold
+	// Matched is false if the regex of the rule did not match. Rules without
+	// a regex always match.
+	Matched bool
+	Groups  []string // Capture groups of the regex, if it matched
+}
+
+// TrackTrace contains the traces of all metadata and chapter rules of a track.
+type TrackTrace struct {
+	Track    Track
+	Metadata []RuleTraceEntry
+	Chapter  []RuleTraceEntry
+}
+
+// RuleTrace returns for every track, in order, what each metadata and chapter
+// rule did.
+func (p *Project) RuleTrace() ([]TrackTrace, error) {
+	tracks, err := p.Tracks()
+	if err != nil {
+		return nil, fmt.Errorf("could not load audio files: %w", err)
+	}
+
+	// the chapter numbers are those of the chapters built from the tracks,
+	// so templates using .Index trace the same titles
+	_, trackChapters, err := p.groupChapters(tracks)
+	if err != nil {
+		return nil, err
+	}
+
+	traces := make([]TrackTrace, 0, len(tracks))
+
+	for i, track := range tracks {
+		metadataTrace, err := track.MetadataTrace()
+		if err != nil {
+			return nil, fmt.Errorf("could not apply metadata rules to %s: %w", track.File, err)
+		}
+
+		_, chapterTrace, err := p.chapterTitle(&track, trackChapters[i])
+		if err != nil {
+			return nil, err
+		}
+
+		traces = append(traces, TrackTrace{Track: track, Metadata: metadataTrace, Chapter: chapterTrace})
+	}
+
+	return traces, nil
+}
+
+// TraceChapterRule validates and applies a single chapter rule to value,
+// without a project.
+func TraceChapterRule(rule ChapterRule, value string) (RuleTraceEntry, error) {
+	if err := rule.Validate(); err != nil {
+		return RuleTraceEntry{}, err
+	}
+
+	entry := RuleTraceEntry{Rule: rule.String(), Before: value}
+	entry.Matched, entry.Groups = regexGroups(rule.Regex, value)
+
+	after, err := rule.Apply(value)
+	if err != nil {
+		return RuleTraceEntry{}, err
+	}
+	entry.After = after
+
+	return entry, nil
+}
+
+// regexGroups returns whether the regex matches value and its capture groups.
+// An empty or invalid regex counts as a match without groups.
+func regexGroups(regex string, value string) (bool, []string) {
+	if regex == "" {
+		return true, nil
+	}
+
+	compiled, err := regexp.Compile(regex)
+	if err != nil {
+		return true, nil
+	}
+
+	matches := compiled.FindStringSubmatch(value)
+	if matches == nil {
+		return false, nil
+	}
+	return true, matches[1:]
+}
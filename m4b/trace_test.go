@@ -0,0 +1,108 @@
+package m4b_test
+
+import (
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleTrace(t *testing.T) {
+	data := map[string]m4b.FileData{
+		"01.flac": {Title: "01 - Der Anfang", Duration: 10, Metadata: ";FFMETADATA1\nalbum=Folge 5: Der Fluch\ntrack=1"},
+	}
+
+	config := m4b.ProjectConfig{
+		MetadataRules: []m4b.MetadataRule{
+			{Type: "regex", Tag: "album", Regex: `Folge (\d): (.*)`, Format: "Folge 00%s: %s"},
+			{Type: "regex", Tag: "album", Regex: `Teil (\d+)`, Format: "%s"},
+			{Type: "set", Tag: "genre", Value: "Hörspiel", When: &m4b.Condition{Track: ">1"}},
+		},
+		ChapterRules: []m4b.ChapterRule{
+			{Regex: `^\d+ - (.+)$`, Format: "%s"},
+		},
+	}
+
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(data, []string{"01.flac"}))
+	require.NoError(t, err)
+
+	traces, err := project.RuleTrace()
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+
+	trace := traces[0]
+	require.Equal(t, "01.flac", trace.Track.File)
+	require.Equal(t, []m4b.RuleTraceEntry{
+		{
+			Index:   0,
+			Rule:    `regex album regex="Folge (\\d): (.*)" format="Folge 00%s: %s"`,
+			Tag:     "album",
+			Before:  "Folge 5: Der Fluch",
+			After:   "Folge 005: Der Fluch",
+			Matched: true,
+			Groups:  []string{"5", "Der Fluch"},
+		},
+		{
+			Index:  1,
+			Rule:   `regex album regex="Teil (\\d+)" format="%s"`,
+			Tag:    "album",
+			Before: "Folge 005: Der Fluch",
+			After:  "Folge 005: Der Fluch",
+		},
+		{
+			Index:   2,
+			Rule:    `set genre value="Hörspiel" (conditional)`,
+			Tag:     "genre",
+			Skipped: true,
+		},
+	}, trace.Metadata)
+
+	require.Len(t, trace.Chapter, 1)
+	require.True(t, trace.Chapter[0].Matched)
+	require.Equal(t, "01 - Der Anfang", trace.Chapter[0].Before)
+	require.Equal(t, "Der Anfang", trace.Chapter[0].After)
+	require.Equal(t, []string{"Der Anfang"}, trace.Chapter[0].Groups)
+}
+
+func TestTraceChapterRule(t *testing.T) {
+	entry, err := m4b.TraceChapterRule(m4b.ChapterRule{Regex: `(\d+)`, Format: "Kapitel %s"}, "12")
+	require.NoError(t, err)
+	require.True(t, entry.Matched)
+	require.Equal(t, "Kapitel 12", entry.After)
+
+	_, err = m4b.TraceChapterRule(m4b.ChapterRule{Regex: `(`, Format: "%s"}, "12")
+	require.ErrorContains(t, err, "regex '(' is invalid")
+}
+
+func TestRuleTrace_ChapterIndex(t *testing.T) {
+	config := m4b.ProjectConfig{
+		ProjectPath:  "/book",
+		HasChapters:  true,
+		Chapters:     m4b.ChaptersConfig{Title: "template", Template: "{{.Index}}. {{.title}}"},
+		ChapterRules: []m4b.ChapterRule{{Regex: `^(\d+)\. (.+)$`, Format: "%s - %s"}},
+	}
+	project, err := m4b.NewProjectWithDeps(config, fakeDeps(chapterData, chapterFiles))
+	require.NoError(t, err)
+
+	traces, err := project.RuleTrace()
+	require.NoError(t, err)
+
+	var titles []string
+	for _, trace := range traces {
+		require.Len(t, trace.Chapter, 1)
+		titles = append(titles, trace.Chapter[0].After)
+	}
+
+	// tracks sharing a chapter trace the index of the chapter, like the chapters
+	require.Equal(t, []string{
+		"1 - Intro", "2 - Intermezzo", "3 - Kapitel 1", "3 - Kapitel 1", "4 - Intermezzo", "4 - Intermezzo",
+	}, titles)
+
+	chapters, err := project.ChapterList()
+	require.NoError(t, err)
+	var chapterTitles []string
+	for _, chapter := range chapters {
+		chapterTitles = append(chapterTitles, chapter.Title())
+	}
+	require.Equal(t, []string{"1 - Intro", "2 - Intermezzo", "3 - Kapitel 1", "4 - Intermezzo"}, chapterTitles)
+}
@@ -1,21 +1,36 @@
 package m4b
 
 import (
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Track represents an audio track with its file path and associated metadata.
 // It contains the file path, a map of metadata tags, and the order in which
 // the tags should be preserved.
 type Track struct {
-	File          string // Path to the audio file
-	MetadataRules []MetadataRule
-	metadataCache map[string]string // Map of metadata tags and their values
-	tagOrder      []string          // Ordered list of metadata tag names
-	rawMetadata   string
-	title         string
-	duration      float64
+	File              string // Path to the audio file
+	PathRules         []PathRule
+	DiscDetection     DiscDetectionConfig
+	MetadataRules     []MetadataRule
+	sidecars          map[string]Sidecar // Sidecar files of the project, merged before the rules
+	sidecarPrecedence []string
+	metadataCache     map[string]string // Map of metadata tags and their values
+	tagOrder          []string          // Ordered list of metadata tag names
+	rawMetadata       string
+	title             string
+	duration          float64
+	start             float64 // Offset within File for tracks of a cue sheet
+	end               float64
+	cueSheet          string           // Cue sheet the track was read from, if any
+	projectPath       string           // Directory of the project, used for relative paths
+	trace             []RuleTraceEntry // What the metadata rules did, see MetadataTrace
+	discDir           string           // Directory the disc number was inferred from, if any
+	orderKey          string           // What decided the position of the track, see OrderKey
+	modTime           time.Time        // Modification time, only set for mtime ordering
 }
 
 // DiscNumber returns the disc number from the track's metadata.
@@ -23,18 +38,8 @@ type Track struct {
 // Returns the disc number and true if successfully parsed, or 0 and false
 // if the tag is missing or invalid.
 func (t *Track) DiscNumber() (int, bool) {
-	disc, exists := t.MetadataTag("disc")
-	if !exists || disc == "" {
-		return 0, false
-	}
-
-	parts := strings.Split(disc, "/")
-
-	discNumber, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return 0, false
-	}
-	return discNumber, true
+	disc, _ := t.MetadataTag("disc")
+	return parseNumberTag(disc)
 }
 
 // TrackNumber returns the track number from the track's metadata.
@@ -42,18 +47,43 @@ func (t *Track) DiscNumber() (int, bool) {
 // Returns the track number and true if successfully parsed, or 0 and false
 // if the tag is missing or invalid.
 func (t *Track) TrackNumber() (int, bool) {
-	track, exists := t.MetadataTag("track")
-	if !exists || track == "" {
-		return 0, false
-	}
+	track, _ := t.MetadataTag("track")
+	return parseNumberTag(track)
+}
+
+// Segment returns the offsets within File in seconds for tracks read from a
+// cue sheet. For regular tracks it returns false.
+func (t *Track) Segment() (float64, float64, bool) {
+	return t.start, t.end, t.cueSheet != ""
+}
+
+// OrderKey describes what decided the position of the track in the project,
+// e.g. "disc 1, track 3" or "playlist entry 4".
+func (t *Track) OrderKey() string {
+	return t.orderKey
+}
 
-	parts := strings.Split(track, "/")
+// RelativePath returns the path of the track relative to the project
+// directory, using / as separator. If the project directory is unknown, the
+// full path is returned.
+func (t *Track) RelativePath() string {
+	if t.projectPath == "" {
+		return filepath.ToSlash(t.File)
+	}
 
-	trackNumber, err := strconv.Atoi(parts[0])
+	rel, err := filepath.Rel(t.projectPath, t.File)
 	if err != nil {
-		return 0, false
+		return filepath.ToSlash(t.File)
 	}
-	return trackNumber, true
+	return filepath.ToSlash(rel)
+}
+
+// MetadataTrace returns what every metadata rule did to the track's tags.
+func (t *Track) MetadataTrace() ([]RuleTraceEntry, error) {
+	if _, _, err := t.Metadata(); err != nil {
+		return nil, err
+	}
+	return t.trace, nil
 }
 
 func (t *Track) Metadata() (map[string]string, []string, error) {
@@ -61,18 +91,78 @@ func (t *Track) Metadata() (map[string]string, []string, error) {
 		metadata := t.rawMetadata
 		tags, tagOrder := t.getMetadataTags(metadata)
 
-		for _, rule := range t.MetadataRules {
+		projectName := ""
+		if t.projectPath != "" {
+			projectName = filepath.Base(t.projectPath)
+		}
+
+		for _, rule := range t.PathRules {
+			set, err := rule.Apply(projectName, t.RelativePath(), tags)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for _, tagName := range set {
+				if !slices.Contains(tagOrder, tagName) {
+					tagOrder = append(tagOrder, tagName)
+				}
+			}
+		}
+
+		t.discDir = ""
+		if tags["disc"] == "" {
+			disc, dir, found, err := t.DiscDetection.Detect(t.RelativePath())
+			if err != nil {
+				return nil, nil, err
+			}
+			if found {
+				if _, exists := tags["disc"]; !exists {
+					tagOrder = append(tagOrder, "disc")
+				}
+				tags["disc"] = strconv.Itoa(disc)
+				t.discDir = dir
+			}
+		}
+
+		// sidecars are merged before the rules, so rules can fix their values
+		tags, tagOrder = mergeSidecars(tags, tagOrder, t.sidecars, t.sidecarPrecedence)
+
+		trace := make([]RuleTraceEntry, 0, len(t.MetadataRules))
+
+		for i, rule := range t.MetadataRules {
 			// Normalize tag name to lowercase for case-insensitive matching
 			tagName := strings.ToLower(rule.Tag)
 
 			// Track which tags existed before applying the rule
-			_, existedBefore := tags[tagName]
+			before, existedBefore := tags[tagName]
+			entry := RuleTraceEntry{Index: i, Rule: rule.String(), Tag: tagName, Before: before}
+
+			if rule.When != nil {
+				holds, err := rule.When.Evaluate(t.ruleContext(tags))
+				if err != nil {
+					return nil, nil, err
+				}
+				if !holds {
+					entry.Skipped = true
+					entry.After = before
+					trace = append(trace, entry)
+					continue
+				}
+			}
+
+			entry.Matched = true
+			if rule.Type == "regex" || rule.Type == "replace" {
+				entry.Matched, entry.Groups = regexGroups(rule.Regex, before)
+			}
 
 			err := rule.Apply(tags)
 			if err != nil {
 				return nil, nil, err
 			}
 
+			entry.After = tags[tagName]
+			trace = append(trace, entry)
+
 			// If the tag didn't exist before but exists now (e.g., from "set" rule),
 			// add it to the tagOrder
 			if !existedBefore {
@@ -82,8 +172,15 @@ func (t *Track) Metadata() (map[string]string, []string, error) {
 			}
 		}
 
+		// Drop tags removed by rules (e.g. "delete" or "move")
+		tagOrder = slices.DeleteFunc(tagOrder, func(tag string) bool {
+			_, exists := tags[tag]
+			return !exists
+		})
+
 		t.metadataCache = tags
 		t.tagOrder = tagOrder
+		t.trace = trace
 	}
 
 	return t.metadataCache, t.tagOrder, nil
@@ -105,32 +202,100 @@ func (t *Track) TitleAndDuration() (string, float64, error) {
 	return t.title, t.duration, nil
 }
 
+func (t *Track) ruleContext(tags map[string]string) RuleContext {
+	return RuleContext{Tags: tags, Path: t.RelativePath(), Duration: t.duration}
+}
+
+// getMetadataTags parses metadata in FFmpeg metadata format. The values are
+// unescaped, they are only escaped again by formatMetadata.
 func (t *Track) getMetadataTags(metadata string) (map[string]string, []string) {
 	var tags = make(map[string]string)
 
 	lines := strings.Split(metadata, "\n")[1:]
 	tagOrder := make([]string, 0, len(lines))
-	for _, line := range lines {
-		split := strings.SplitN(line, "=", 2)
-		if len(split) < 1 {
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		// an escaped newline continues the value on the next line
+		for endsWithEscape(line) && i+1 < len(lines) {
+			i++
+			line += "\n" + lines[i]
+		}
+
+		if line == "" {
 			continue
 		}
 
+		name, value, _ := cutUnescaped(line, '=')
+
 		// Normalize tag names to lowercase for case-insensitive matching
-		tagName := strings.ToLower(split[0])
+		tagName := strings.ToLower(unescapeMetadataValue(name))
 
 		// Only add to tagOrder if this tag hasn't been seen before
 		if _, exists := tags[tagName]; !exists {
 			tagOrder = append(tagOrder, tagName)
 		}
 
-		if len(split) == 1 {
-			tags[tagName] = ""
+		tags[tagName] = unescapeMetadataValue(value)
+	}
+
+	return tags, tagOrder
+}
+
+// formatMetadata renders the tags in FFmpeg metadata format. Tags hold plain
+// values everywhere else, this is the only place they are escaped.
+func formatMetadata(tags map[string]string, tagOrder []string) string {
+	lines := []string{";FFMETADATA1"}
+	for _, tag := range tagOrder {
+		lines = append(lines, escapeMetadataValue(tag)+"="+escapeMetadataValue(tags[tag]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeMetadataValue escapes the special characters of the FFmpeg metadata format.
+func escapeMetadataValue(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		"=", `\=`,
+		";", `\;`,
+		"#", `\#`,
+		"\n", "\\\n",
+	)
+	return replacer.Replace(value)
+}
+
+// unescapeMetadataValue reverts escapeMetadataValue.
+func unescapeMetadataValue(value string) string {
+	var sb strings.Builder
+	escaped := false
+	for _, r := range value {
+		if r == '\\' && !escaped {
+			escaped = true
 			continue
 		}
+		escaped = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
 
-		tags[tagName] = split[1]
+// cutUnescaped slices s around the first separator that is not escaped.
+func cutUnescaped(s string, separator rune) (string, string, bool) {
+	escaped := false
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == separator:
+			return s[:i], s[i+1:], true
+		}
 	}
+	return s, "", false
+}
 
-	return tags, tagOrder
+// endsWithEscape reports whether line ends with an escaping backslash.
+func endsWithEscape(line string) bool {
+	trailing := len(line) - len(strings.TrimRight(line, `\`))
+	return trailing%2 == 1
 }
@@ -0,0 +1,172 @@
+package m4b
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultsFileName = "defaults.yaml"
+
+// trackNumberFromFilename fills the track number of untagged files from the
+// leading digits of the filename, e.g. "03 - Kapitel 3.flac".
+var trackNumberFromFilename = PathRule{Regex: `^(?P<track>\d+)`, Source: "filename"}
+
+// albumFromProjectDir fills the album of untagged books in multi mode from
+// the name of each book's directory.
+var albumFromProjectDir = PathRule{Regex: `^(?P<album>.+)$`, Source: "project"}
+
+// DefaultsPath returns the path of the global defaults (~/.config/narr/defaults.yaml).
+func DefaultsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user home dir: %w", err)
+	}
+
+	return filepath.Join(home, ".config", "narr", defaultsFileName), nil
+}
+
+// ReadDefaults reads the global defaults, a ProjectConfig that zero-config
+// mode starts from. A missing defaults file results in an empty config.
+// Preset paths are made absolute, so the config can be saved anywhere.
+func ReadDefaults() (ProjectConfig, error) {
+	path, err := DefaultsPath()
+	if err != nil {
+		return ProjectConfig{}, err
+	}
+
+	bytes, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ProjectConfig{}, nil
+	}
+	if err != nil {
+		return ProjectConfig{}, fmt.Errorf("could not read defaults %s: %w", path, err)
+	}
+
+	var defaults ProjectConfig
+	if err = yaml.Unmarshal(bytes, &defaults); err != nil {
+		return ProjectConfig{}, fmt.Errorf("could not unmarshal defaults %s: %w", path, err)
+	}
+
+	for i, ref := range defaults.Presets {
+		if isPresetPath(ref) && !filepath.IsAbs(ref) {
+			defaults.Presets[i] = filepath.Join(filepath.Dir(path), ref)
+		}
+	}
+
+	return defaults, nil
+}
+
+// ZeroConfig builds the config for a directory without narr.yaml. It starts
+// from the global defaults, adds the settings suggested by SuggestConfig and
+// infers album, artist and track numbers of untagged files from the folder
+// structure. Every inferred setting is explained in the reasons.
+func ZeroConfig(path string, defaults ProjectConfig, deps ProjectDependencies) (*ConfigSuggestion, error) {
+	suggestion, err := SuggestConfig(path, deps)
+	if err != nil {
+		return nil, err
+	}
+
+	config := defaults
+	config.ProjectPath = ""
+	config.PathRules = slices.Clone(defaults.PathRules)
+	config.MetadataRules = slices.Clone(defaults.MetadataRules)
+	config.ChapterRules = slices.Concat(defaults.ChapterRules, suggestion.Config.ChapterRules)
+	config.HasChapters = defaults.HasChapters || suggestion.Config.HasChapters
+	config.Multi = defaults.Multi || suggestion.Config.Multi
+	if config.CoverPath == "" {
+		config.CoverPath = suggestion.Config.CoverPath
+	}
+	if config.MetadataRules == nil {
+		config.MetadataRules = []MetadataRule{}
+	}
+	if config.ChapterRules == nil {
+		config.ChapterRules = []ChapterRule{}
+	}
+
+	reasons := suggestion.Reasons
+	if len(defaults.Presets) > 0 {
+		reasons["presets"] = "from the global defaults"
+	}
+
+	// infer from the tracks with all rules applied so far
+	probe := config
+	if err = probe.expandPresets(path); err != nil {
+		return nil, err
+	}
+	probe.ProjectPath = suggestion.TrackDir
+	probe.Multi = false
+
+	project, err := NewProjectWithDeps(probe, deps)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks, err := project.Tracks()
+	if err != nil {
+		return nil, fmt.Errorf("could not load tracks: %w", err)
+	}
+
+	missing := map[string]bool{}
+	for _, track := range tracks {
+		tags, _, err := track.Metadata()
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range []string{"album", "artist", "track"} {
+			if tags[tag] == "" {
+				missing[tag] = true
+			}
+		}
+	}
+
+	var inferred, inferredFromPath []string
+
+	if missing["track"] {
+		config.PathRules = append(config.PathRules, trackNumberFromFilename)
+		inferredFromPath = append(inferredFromPath, "track numbers of untagged files are taken from the filenames")
+	}
+
+	// in multi mode the books are the subdirectories, so album varies per
+	// project and the artist is the folder containing the books
+	artistDir := filepath.Dir(path)
+	switch {
+	case config.Multi:
+		artistDir = path
+		if missing["album"] {
+			config.PathRules = append(config.PathRules, albumFromProjectDir)
+			inferredFromPath = append(inferredFromPath, "the album of untagged books is taken from their folder name")
+		}
+	case missing["album"]:
+		config.MetadataRules = append(config.MetadataRules, MetadataRule{
+			Type:  "default",
+			Tag:   "album",
+			Value: filepath.Base(path),
+		})
+		inferred = append(inferred, "album from the folder name")
+	}
+
+	if len(inferredFromPath) > 0 {
+		reasons["pathRules"] = "inferred: " + strings.Join(inferredFromPath, ", ")
+	}
+
+	if parent := filepath.Base(artistDir); missing["artist"] && parent != string(filepath.Separator) {
+		config.MetadataRules = append(config.MetadataRules, MetadataRule{
+			Type:  "default",
+			Tag:   "artist",
+			Value: parent,
+		})
+		inferred = append(inferred, "artist from the parent folder name")
+	}
+
+	if len(inferred) > 0 {
+		reasons["metadataRules"] = "inferred: " + strings.Join(inferred, ", ")
+	}
+
+	return &ConfigSuggestion{Config: config, Reasons: reasons, TrackDir: suggestion.TrackDir}, nil
+}
@@ -0,0 +1,108 @@
+package m4b_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/achwo/narr/m4b"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroConfig(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Tolkien", "Der Hobbit")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	one := filepath.Join(dir, "01 - Anfang.flac")
+	two := filepath.Join(dir, "02 - Ende.flac")
+	data := map[string]m4b.FileData{
+		one: {Duration: 10, Metadata: ";FFMETADATA1\ngenre=Fantasy"},
+		two: {Duration: 10, Metadata: ";FFMETADATA1\ngenre=Fantasy"},
+	}
+	deps := fakeDeps(data, []string{two, one})
+
+	defaults := m4b.ProjectConfig{
+		ShouldConvert: true,
+		MetadataRules: []m4b.MetadataRule{{Type: "set", Tag: "genre", Value: "Hörbuch"}},
+	}
+
+	suggestion, err := m4b.ZeroConfig(dir, defaults, deps)
+	require.NoError(t, err)
+
+	config := suggestion.Config
+	require.True(t, config.ShouldConvert)
+	require.Len(t, config.PathRules, 1)
+	require.Equal(t, []m4b.MetadataRule{
+		{Type: "set", Tag: "genre", Value: "Hörbuch"},
+		{Type: "default", Tag: "album", Value: "Der Hobbit"},
+		{Type: "default", Tag: "artist", Value: "Tolkien"},
+	}, config.MetadataRules)
+
+	out, err := suggestion.YAML()
+	require.NoError(t, err)
+	require.Contains(t, string(out), "# inferred: album from the folder name, artist from the parent folder name")
+
+	config.ProjectPath = dir
+	project, err := m4b.NewProjectWithDeps(config, deps)
+	require.NoError(t, err)
+
+	metadata, err := project.Metadata()
+	require.NoError(t, err)
+	require.Equal(t, ";FFMETADATA1\ngenre=Hörbuch\nalbum=Der Hobbit\nartist=Tolkien", metadata)
+
+	tracks, err := project.Tracks()
+	require.NoError(t, err)
+	require.Equal(t, one, tracks[0].File)
+}
+
+func TestZeroConfig_TaggedFiles(t *testing.T) {
+	data := map[string]m4b.FileData{
+		"1.flac": {Duration: 10, Metadata: ";FFMETADATA1\nalbum=Book\nartist=Author\ntrack=1"},
+	}
+
+	suggestion, err := m4b.ZeroConfig(t.TempDir(), m4b.ProjectConfig{}, fakeDeps(data, []string{"1.flac"}))
+	require.NoError(t, err)
+	require.Empty(t, suggestion.Config.PathRules)
+	require.Empty(t, suggestion.Config.MetadataRules)
+}
+
+func TestZeroConfig_MultiUntaggedBooks(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "Tolkien")
+	hobbit := filepath.Join(root, "Der Hobbit")
+	silmarillion := filepath.Join(root, "Silmarillion")
+	require.NoError(t, os.MkdirAll(hobbit, 0755))
+	require.NoError(t, os.MkdirAll(silmarillion, 0755))
+
+	one := filepath.Join(hobbit, "01.flac")
+	two := filepath.Join(silmarillion, "01.flac")
+	data := map[string]m4b.FileData{
+		one: {Duration: 10, Metadata: ";FFMETADATA1\ntrack=1"},
+		two: {Duration: 10, Metadata: ";FFMETADATA1\ntrack=1"},
+	}
+	processor := &m4b.NullAudioProcessor{Data: data}
+	deps := m4b.ProjectDependencies{
+		AudioFileProvider: &dirAudioFileProvider{Files: map[string][]string{
+			root:         {one, two},
+			hobbit:       {one},
+			silmarillion: {two},
+		}},
+		AudioProcessor: processor,
+		TrackFactory:   &m4b.FFmpegTrackFactory{AudioProcessor: processor},
+	}
+
+	suggestion, err := m4b.ZeroConfig(root, m4b.ProjectConfig{Multi: true}, deps)
+	require.NoError(t, err)
+	require.Contains(t, suggestion.Reasons["pathRules"], "the album of untagged books is taken from their folder name")
+
+	for _, dir := range []string{hobbit, silmarillion} {
+		config := suggestion.Config
+		config.ProjectPath = dir
+		project, err := m4b.NewProjectWithDeps(config, deps)
+		require.NoError(t, err)
+
+		artist, album, err := project.ArtistAndBookTitle()
+		require.NoError(t, err)
+		require.Equal(t, "Tolkien", artist)
+		require.Equal(t, filepath.Base(dir), album)
+	}
+}
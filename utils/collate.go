@@ -0,0 +1,317 @@
+package utils
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Collator compares strings for sorting, following a subset of the Unicode
+// Collation Algorithm: letters are compared case- and accent-insensitively
+// first ("Ärzte" sorts with "Arzte", not after "Z"), then by accents, then
+// by case. Spaces and punctuation are ignored unless everything else is
+// equal. Digit sequences are compared numerically and sort before letters.
+//
+// Optionally, roman numerals and spelled-out numbers are compared as numbers,
+// so that "Teil IV" sorts before "Teil X" and "Part Two" before "Part Three".
+type Collator struct {
+	// RomanNumerals parses upper-case roman numerals (I, IV, XII) as numbers
+	// when they follow a keyword like Part or Teil, see romanKeywords.
+	RomanNumerals bool `yaml:"romanNumerals,omitempty"`
+	// SpelledNumbers parses spelled-out numbers up to 99 (two, twenty-one,
+	// zwei, einundzwanzig) as numbers.
+	SpelledNumbers bool `yaml:"spelledNumbers,omitempty"`
+	// Locale restricts the spelled-out numbers to one language: "en" or "de".
+	// Both are recognized if empty.
+	Locale string `yaml:"locale,omitempty"`
+}
+
+var romanNumeral = regexp.MustCompile(`^M{0,4}(CM|CD|D?C{0,3})(XC|XL|L?X{0,3})(IX|IV|V?I{0,3})$`)
+
+// romanKeywords are the words a roman numeral has to follow to be parsed, so
+// words like CD or MIX are not taken for numbers.
+var romanKeywords = []string{
+	"part", "book", "chapter", "volume", "vol", "act", "episode", "season",
+	"teil", "buch", "kapitel", "band", "akt", "folge", "staffel",
+}
+
+var romanValues = map[rune]int{'I': 1, 'V': 5, 'X': 10, 'L': 50, 'C': 100, 'D': 500, 'M': 1000}
+
+var spelledUnits = map[string]map[string]int{
+	"en": {
+		"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7,
+		"eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12, "thirteen": 13,
+		"fourteen": 14, "fifteen": 15, "sixteen": 16, "seventeen": 17, "eighteen": 18, "nineteen": 19,
+	},
+	"de": {
+		"null": 0, "eins": 1, "zwei": 2, "drei": 3, "vier": 4, "fünf": 5, "sechs": 6, "sieben": 7,
+		"acht": 8, "neun": 9, "zehn": 10, "elf": 11, "zwölf": 12, "dreizehn": 13, "vierzehn": 14,
+		"fünfzehn": 15, "sechzehn": 16, "siebzehn": 17, "achtzehn": 18, "neunzehn": 19,
+	},
+}
+
+var spelledTens = map[string]map[string]int{
+	"en": {
+		"twenty": 20, "thirty": 30, "forty": 40, "fifty": 50,
+		"sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+	},
+	"de": {
+		"zwanzig": 20, "dreißig": 30, "vierzig": 40, "fünfzig": 50,
+		"sechzig": 60, "siebzig": 70, "achtzig": 80, "neunzig": 90,
+	},
+}
+
+// foldings maps letters with diacritics to their base letters.
+var foldings = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i",
+	'ł': "l", 'ľ': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ß': "ss", 'ś': "s", 'ş': "s", 'š': "s", 'ť': "t", 'ţ': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z", 'þ': "th", 'ð': "d",
+}
+
+// Validate checks that the locale is supported.
+func (c Collator) Validate() error {
+	if _, ok := spelledUnits[c.Locale]; c.Locale != "" && !ok {
+		return fmt.Errorf("unsupported locale '%s', expected en or de", c.Locale)
+	}
+	return nil
+}
+
+type collationToken struct {
+	raw      string // as written
+	lower    string // lower-cased, with diacritics
+	folded   string // lower-cased, without diacritics
+	digits   string // value of number tokens, without leading zeros
+	isNumber bool
+}
+
+// Compare returns a negative number if a sorts before b, a positive number if
+// it sorts after b and 0 if both are equal.
+func (c Collator) Compare(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	ta := c.tokenize(a)
+	tb := c.tokenize(b)
+
+	levels := []func(x, y collationToken) int{
+		comparePrimary,
+		func(x, y collationToken) int { return strings.Compare(x.lower, y.lower) },
+		func(x, y collationToken) int { return compareCase(x.raw, y.raw) },
+		// leading zeros: "1" before "01"
+		func(x, y collationToken) int { return cmp.Compare(len(x.raw), len(y.raw)) },
+	}
+
+	for _, level := range levels {
+		for i := 0; i < len(ta) && i < len(tb); i++ {
+			if result := level(ta[i], tb[i]); result != 0 {
+				return result
+			}
+		}
+		if len(ta) != len(tb) {
+			return cmp.Compare(len(ta), len(tb))
+		}
+	}
+
+	// only spaces and punctuation differ
+	return strings.Compare(a, b)
+}
+
+func comparePrimary(x, y collationToken) int {
+	switch {
+	case x.isNumber && y.isNumber:
+		return cmp.Or(cmp.Compare(len(x.digits), len(y.digits)), strings.Compare(x.digits, y.digits))
+	case x.isNumber:
+		return -1
+	case y.isNumber:
+		return 1
+	default:
+		return strings.Compare(x.folded, y.folded)
+	}
+}
+
+// compareCase sorts lower case before upper case.
+func compareCase(a, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+	for i := 0; i < len(ra) && i < len(rb); i++ {
+		lowerA, lowerB := unicode.IsLower(ra[i]), unicode.IsLower(rb[i])
+		if lowerA != lowerB {
+			if lowerA {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// tokenize splits s into words and numbers, dropping spaces and punctuation.
+func (c Collator) tokenize(s string) []collationToken {
+	var tokens []collationToken
+	// separators between the previous token and the current position
+	separator := ""
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsDigit(r):
+			start := i
+			var digits strings.Builder
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				digits.WriteByte(byte('0' + digitValue(runes[i])))
+				i++
+			}
+			value := strings.TrimLeft(digits.String(), "0")
+			if value == "" {
+				value = "0"
+			}
+			tokens = append(tokens, collationToken{raw: string(runes[start:i]), digits: value, isNumber: true})
+		case unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.Is(unicode.Mn, runes[i])) {
+				i++
+			}
+			previous := ""
+			if n := len(tokens); n > 0 {
+				previous = tokens[n-1].lower
+			}
+			token := c.wordToken(string(runes[start:i]), previous)
+
+			// "twenty-one", "twenty one"
+			if n := len(tokens); n > 0 && token.isNumber && (separator == " " || separator == "-") {
+				if previous := tokens[n-1]; c.isSpelledTens(previous.raw) && len(token.digits) == 1 && token.digits != "0" {
+					tokens[n-1].raw += separator + token.raw
+					tokens[n-1].digits = previous.digits[:1] + token.digits
+					separator = ""
+					continue
+				}
+			}
+			tokens = append(tokens, token)
+		default:
+			separator += string(r)
+			i++
+			continue
+		}
+
+		separator = ""
+	}
+
+	return tokens
+}
+
+// wordToken turns word into a token, previous is the lower-cased token before it.
+func (c Collator) wordToken(word string, previous string) collationToken {
+	lower := strings.ToLower(word)
+
+	if value, ok := c.parseNumberWord(word, lower, previous); ok {
+		return collationToken{raw: word, lower: lower, digits: strconv.Itoa(value), isNumber: true}
+	}
+
+	var folded strings.Builder
+	for _, r := range lower {
+		if unicode.Is(unicode.Mn, r) {
+			// combining marks of decomposed letters
+			continue
+		}
+		if base, ok := foldings[r]; ok {
+			folded.WriteString(base)
+		} else {
+			folded.WriteRune(r)
+		}
+	}
+
+	return collationToken{raw: word, lower: lower, folded: folded.String()}
+}
+
+func (c Collator) parseNumberWord(word string, lower string, previous string) (int, bool) {
+	if c.RomanNumerals && slices.Contains(romanKeywords, previous) && romanNumeral.MatchString(word) {
+		return parseRoman(word), true
+	}
+
+	if !c.SpelledNumbers {
+		return 0, false
+	}
+
+	for _, locale := range c.locales() {
+		if value, ok := spelledUnits[locale][lower]; ok {
+			return value, true
+		}
+		if value, ok := spelledTens[locale][lower]; ok {
+			return value, true
+		}
+	}
+
+	// German compounds like "einundzwanzig"
+	if c.Locale == "" || c.Locale == "de" {
+		if unit, tens, found := strings.Cut(lower, "und"); found {
+			unitValue, unitOk := spelledUnits["de"][unit]
+			if unit == "ein" {
+				unitValue, unitOk = 1, true
+			}
+			tensValue, tensOk := spelledTens["de"][tens]
+			if unitOk && tensOk && unitValue > 0 && unitValue < 10 {
+				return tensValue + unitValue, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func (c Collator) isSpelledTens(word string) bool {
+	if !c.SpelledNumbers {
+		return false
+	}
+	for _, locale := range c.locales() {
+		if _, ok := spelledTens[locale][strings.ToLower(word)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Collator) locales() []string {
+	if c.Locale == "" {
+		return []string{"en", "de"}
+	}
+	return []string{c.Locale}
+}
+
+func parseRoman(numeral string) int {
+	total := 0
+	for i, r := range numeral {
+		value := romanValues[r]
+		if i+1 < len(numeral) && value < romanValues[rune(numeral[i+1])] {
+			total -= value
+		} else {
+			total += value
+		}
+	}
+	return total
+}
+
+// digitValue returns the value of a decimal digit of any script. Decimal
+// digits are encoded in consecutive runs starting at zero.
+func digitValue(r rune) int {
+	if r >= '0' && r <= '9' {
+		return int(r - '0')
+	}
+	offset := 0
+	for unicode.IsDigit(r - rune(offset+1)) {
+		offset++
+	}
+	return offset % 10
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollator_Compare(t *testing.T) {
+	tests := []struct {
+		name     string
+		collator Collator
+		a        string
+		b        string
+		expected int
+	}{
+		{name: "numbers", a: "file2.txt", b: "file10.txt", expected: -1},
+		{name: "umlaut sorts with base letter", a: "Ärzte", b: "Bären", expected: -1},
+		{name: "accent after plain letter", a: "Arzte", b: "Ärzte", expected: -1},
+		{name: "sharp s", a: "Straße 2", b: "Strasse 10", expected: -1},
+		{name: "case insensitive first", a: "apfel", b: "Birne", expected: -1},
+		{name: "lower case before upper case", a: "apfel", b: "Apfel", expected: -1},
+		{name: "punctuation ignored", a: "Kapitel - 2", b: "Kapitel 10", expected: -1},
+		{name: "numbers before letters", a: "1 Intro", b: "Intro", expected: -1},
+		{name: "non-ascii digits", a: "Teil ٢", b: "Teil 10", expected: -1},
+		{name: "leading zeros", a: "file1.txt", b: "file01.txt", expected: -1},
+		{name: "equal", a: "Kapitel 1", b: "Kapitel 1", expected: 0},
+		{name: "roman disabled", a: "Teil V", b: "Teil IX", expected: 1},
+		{name: "roman", collator: Collator{RomanNumerals: true}, a: "Teil V", b: "Teil IX", expected: -1},
+		{name: "roman and digits", collator: Collator{RomanNumerals: true}, a: "Teil X", b: "Teil 11", expected: -1},
+		{name: "roman after english keyword", collator: Collator{RomanNumerals: true}, a: "Book IV", b: "Book IX", expected: -1},
+		{name: "roman without keyword", collator: Collator{RomanNumerals: true}, a: "CD 2", b: "Anfang", expected: 1},
+		{name: "roman-like words are words", collator: Collator{RomanNumerals: true}, a: "MIX", b: "Anfang", expected: 1},
+		{name: "roman-like word after keyword", collator: Collator{RomanNumerals: true}, a: "Teil I", b: "Teil Ende", expected: -1},
+		{name: "single letter without keyword", collator: Collator{RomanNumerals: true}, a: "I am", b: "Anfang", expected: 1},
+		{name: "spelled disabled", a: "Part Two", b: "Part Three", expected: 1},
+		{name: "spelled", collator: Collator{SpelledNumbers: true}, a: "Part Two", b: "Part Three", expected: -1},
+		{name: "spelled compound", collator: Collator{SpelledNumbers: true}, a: "Part Twenty-One", b: "Part 22", expected: -1},
+		{name: "spelled german", collator: Collator{SpelledNumbers: true}, a: "Teil Zwölf", b: "Teil Einundzwanzig", expected: -1},
+		{name: "locale restricts languages", collator: Collator{SpelledNumbers: true, Locale: "en"}, a: "Teil Zwei", b: "Teil Drei", expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.collator.Compare(tt.a, tt.b)
+			switch {
+			case tt.expected < 0:
+				require.Less(t, result, 0, "expected %s < %s", tt.a, tt.b)
+			case tt.expected > 0:
+				require.Greater(t, result, 0, "expected %s > %s", tt.a, tt.b)
+			default:
+				require.Equal(t, 0, result, "expected %s == %s", tt.a, tt.b)
+			}
+			require.Equal(t, -cmpSign(result), cmpSign(tt.collator.Compare(tt.b, tt.a)))
+		})
+	}
+}
+
+func TestCollator_Sort(t *testing.T) {
+	titles := []string{"Teil X", "Teil II", "Teil I", "Epilog", "Teil IV", "Ärger", "Anfang"}
+	slices.SortFunc(titles, Collator{RomanNumerals: true}.Compare)
+	require.Equal(t, []string{"Anfang", "Ärger", "Epilog", "Teil I", "Teil II", "Teil IV", "Teil X"}, titles)
+}
+
+func cmpSign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
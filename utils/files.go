@@ -2,20 +2,45 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"slices"
 	"strings"
 )
 
+// DefaultAudioExtensions are the extensions of the audio files narr processes
+// by default. Other formats have to be selected explicitly.
+var DefaultAudioExtensions = []string{".m4a", ".mp3", ".flac"}
+
+// IgnoreFileName is the name of the files listing globs of files and
+// directories to skip, relative to the directory containing it.
+const IgnoreFileName = ".narrignore"
+
 // OSAudioFileProvider implements audio file discovery functionality using the OS filesystem
-type OSAudioFileProvider struct{}
+type OSAudioFileProvider struct {
+	Extensions []string // defaults to DefaultAudioExtensions
+	Include    []string // globs, if set only matching files are returned
+	Exclude    []string // globs of files and directories to skip
+	MaxDepth   int      // 1 only searches the directory itself, 0 is unlimited
+}
 
 // AudioFiles returns a list of audio files found at the given path
 func (p *OSAudioFileProvider) AudioFiles(fullPath string) ([]string, error) {
-	return GetFilesByExtensions(fullPath, []string{".m4a", ".mp3", ".flac"})
+	extensions := p.Extensions
+	if len(extensions) == 0 {
+		extensions = DefaultAudioExtensions
+	}
+
+	return FindFiles(fullPath, FileFilter{
+		Extensions: extensions,
+		Include:    p.Include,
+		Exclude:    p.Exclude,
+		MaxDepth:   p.MaxDepth,
+	})
 }
 
 // GetValidFilePathFromArgs retrieves and validates a file path from command line arguments.
@@ -79,26 +104,152 @@ func GetValidFullpathFromArgs(args []string, index int) (string, error) {
 	return fullpath, nil
 }
 
+// FileFilter selects the files returned by FindFiles.
+type FileFilter struct {
+	// Extensions are matched case-insensitively, with or without leading dot.
+	Extensions []string
+	// Include and Exclude are globs (see MatchGlob) over the path relative to
+	// the searched directory. A file has to match one of the includes, if any.
+	// Excluded directories are skipped entirely.
+	Include  []string
+	Exclude  []string
+	MaxDepth int // 1 only searches the directory itself, 0 is unlimited
+}
+
 // GetFilesByExtensions walks through a directory tree and returns all files with any of the
 // specified extensions.
 // It returns an error if there are any issues accessing the filesystem during the walk.
 func GetFilesByExtensions(fullpath string, extensions []string) ([]string, error) {
+	return FindFiles(fullpath, FileFilter{Extensions: extensions})
+}
+
+// FindFiles walks through a directory tree and returns all files selected by
+// the filter. Files and directories listed in a .narrignore are skipped.
+// It returns an error if there are any issues accessing the filesystem during the walk.
+func FindFiles(fullpath string, filter FileFilter) ([]string, error) {
+	extensions := make([]string, 0, len(filter.Extensions))
+	for _, ext := range filter.Extensions {
+		extensions = append(extensions, "."+strings.TrimPrefix(strings.ToLower(ext), "."))
+	}
+
+	// ignore patterns by the directory of their .narrignore
+	ignores := map[string][]string{}
+
 	var files []string
 
-	err := filepath.WalkDir(fullpath, func(path string, d fs.DirEntry, err error) error {
+	err := filepath.WalkDir(fullpath, func(file string, d fs.DirEntry, err error) error {
 		if err != nil {
-			return fmt.Errorf("failed to access %s: %w", path, err)
+			return fmt.Errorf("failed to access %s: %w", file, err)
 		}
 
-		if slices.Contains(extensions, filepath.Ext(path)) {
-			files = append(files, path)
+		rel, err := filepath.Rel(fullpath, file)
+		if err != nil {
+			return err
 		}
+		rel = filepath.ToSlash(rel)
+
+		if rel != "." {
+			ignored, err := isIgnored(fullpath, rel, ignores, filter.Exclude)
+			if err != nil {
+				return err
+			}
+			if ignored {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		if d.IsDir() {
+			if filter.MaxDepth > 0 && rel != "." && strings.Count(rel, "/")+1 >= filter.MaxDepth {
+				return filepath.SkipDir
+			}
+
+			patterns, err := readIgnoreFile(filepath.Join(file, IgnoreFileName))
+			if err != nil {
+				return err
+			}
+			ignores[rel] = patterns
+			return nil
+		}
+
+		if !slices.Contains(extensions, strings.ToLower(filepath.Ext(file))) {
+			return nil
+		}
+
+		if len(filter.Include) > 0 {
+			included, err := matchAnyGlob(filter.Include, rel)
+			if err != nil || !included {
+				return err
+			}
+		}
+
+		files = append(files, file)
 		return nil
 	})
 
 	return files, err
 }
 
+// isIgnored reports whether rel is excluded or listed in the .narrignore of
+// one of its parent directories.
+func isIgnored(root string, rel string, ignores map[string][]string, exclude []string) (bool, error) {
+	if excluded, err := matchAnyGlob(exclude, rel); err != nil || excluded {
+		return excluded, err
+	}
+
+	for dir := path.Dir(rel); ; dir = path.Dir(dir) {
+		relToDir := rel
+		if dir != "." {
+			relToDir = strings.TrimPrefix(rel, dir+"/")
+		}
+
+		if ignored, err := matchAnyGlob(ignores[dir], relToDir); err != nil || ignored {
+			return ignored, err
+		}
+
+		if dir == "." {
+			return false, nil
+		}
+	}
+}
+
+func matchAnyGlob(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := MatchGlob(strings.TrimSuffix(pattern, "/"), name)
+		if err != nil {
+			return false, fmt.Errorf("invalid glob '%s': %w", pattern, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// readIgnoreFile returns the globs of an ignore file, skipping empty lines
+// and comments. A missing file results in no globs.
+func readIgnoreFile(file string) ([]string, error) {
+	bytes, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	var patterns []string
+	for _, line := range strings.Split(string(bytes), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, strings.TrimPrefix(line, "/"))
+	}
+	return patterns, nil
+}
+
 func GetAllFilesByName(basepath string, name string) ([]string, error) {
 	var files []string
 	err := filepath.WalkDir(basepath, func(path string, d fs.DirEntry, err error) error {
@@ -118,3 +269,40 @@ func GetAllFilesByName(basepath string, name string) ([]string, error) {
 	})
 	return files, err
 }
+
+// MatchGlob reports whether name, a path separated by /, matches the glob
+// pattern. Besides the syntax of path.Match, "**" matches any number of path
+// segments. A pattern without / is matched against the last segment only.
+func MatchGlob(pattern string, name string) (bool, error) {
+	if !strings.Contains(pattern, "/") {
+		return path.Match(pattern, path.Base(name))
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern []string, name []string) (bool, error) {
+	if len(pattern) == 0 {
+		return len(name) == 0, nil
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matched, err := matchSegments(pattern[1:], name[i:]); err != nil || matched {
+				return matched, err
+			}
+		}
+		return false, nil
+	}
+
+	if len(name) == 0 {
+		return false, nil
+	}
+
+	matched, err := path.Match(pattern[0], name[0])
+	if err != nil || !matched {
+		return false, err
+	}
+
+	return matchSegments(pattern[1:], name[1:])
+}
@@ -0,0 +1,100 @@
+package utils_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/achwo/narr/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"*.flac", "CD1/01.flac", true},
+		{"01.flac", "CD1/01.flac", true},
+		{"CD1/*.flac", "CD1/01.flac", true},
+		{"CD1/*.flac", "CD2/01.flac", false},
+		{"CD1/*", "CD1/sub/01.flac", false},
+		{"**/*.mp3", "a/b/c.mp3", true},
+		{"**/*.mp3", "c.mp3", true},
+		{"a/**", "a/b/c.mp3", true},
+		{"intro.mp3", "outro.mp3", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			matched, err := utils.MatchGlob(tt.pattern, tt.name)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, matched)
+		})
+	}
+}
+
+func TestMatchGlob_Invalid(t *testing.T) {
+	_, err := utils.MatchGlob("[", "a")
+	require.Error(t, err)
+}
+
+func TestFindFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"01.mp3", "02.MP3", "03.Opus", "cover.jpg",
+		"CD1/01.flac", "CD1/Sample/01.flac",
+		"Bonus/01.mp3", "Bonus/Extra/02.mp3",
+		"Ignored/01.mp3", "CD2/01.flac", "CD2/skip.flac",
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte{}, 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, utils.IgnoreFileName), []byte("# comment\n\nIgnored/\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "CD2", utils.IgnoreFileName), []byte("skip.*"), 0644))
+
+	tests := []struct {
+		name     string
+		filter   utils.FileFilter
+		expected []string
+	}{
+		{
+			name:     "case-insensitive extensions",
+			filter:   utils.FileFilter{Extensions: []string{"mp3", ".opus"}},
+			expected: []string{"01.mp3", "02.MP3", "03.Opus", "Bonus/01.mp3", "Bonus/Extra/02.mp3"},
+		},
+		{
+			name:     "exclude directories by name",
+			filter:   utils.FileFilter{Extensions: utils.DefaultAudioExtensions, Exclude: []string{"Sample", "Bonus/**"}},
+			expected: []string{"01.mp3", "02.MP3", "CD1/01.flac", "CD2/01.flac"},
+		},
+		{
+			name:     "include",
+			filter:   utils.FileFilter{Extensions: utils.DefaultAudioExtensions, Include: []string{"CD*/*"}},
+			expected: []string{"CD1/01.flac", "CD2/01.flac"},
+		},
+		{
+			name:     "max depth",
+			filter:   utils.FileFilter{Extensions: utils.DefaultAudioExtensions, MaxDepth: 2},
+			expected: []string{"01.mp3", "02.MP3", "Bonus/01.mp3", "CD1/01.flac", "CD2/01.flac"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := utils.FindFiles(dir, tt.filter)
+			require.NoError(t, err)
+
+			var rel []string
+			for _, file := range found {
+				r, err := filepath.Rel(dir, file)
+				require.NoError(t, err)
+				rel = append(rel, filepath.ToSlash(r))
+			}
+			require.ElementsMatch(t, tt.expected, rel)
+		})
+	}
+}
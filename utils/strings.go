@@ -10,21 +10,32 @@ import (
 	"unicode"
 )
 
+// formatVerbRegex matches the verbs of a format string understood by ApplyRegex,
+// including flags and width like %02d, and the escaped %%.
+var formatVerbRegex = regexp.MustCompile(`%[-+ #0]*\d*[sd%]`)
+
 // ApplyRegex applies a regular expression pattern to an input string and formats the captured groups
-// using the provided format string. The format string should contain %s placeholders that match
-// the number of capture groups in the regex pattern.
+// using the provided format string. The format string should contain a %s or %d placeholder for
+// each capture group in the regex pattern. For %d, the capture group has to be a number, so
+// "%02d" turns "1" into "01".
 //
 // Parameters:
 //   - input: The input string to apply the regex pattern to
 //   - regex: A compiled regular expression pattern with capture groups
-//   - format: A format string containing %s placeholders for each capture group
+//   - format: A format string containing %s or %d placeholders for each capture group
 //
 // Returns:
 //   - string: The formatted result using captured groups, or the original input if there's an error
-//   - error: An error if the input doesn't match the pattern or if the number of capture groups
-//     doesn't match the format string placeholders
+//   - error: An error if the input doesn't match the pattern, if the number of capture groups
+//     doesn't match the format string placeholders or if a group for %d is not a number
 func ApplyRegex(input string, regex *regexp.Regexp, format string) (string, error) {
-	expectedMatchLen := strings.Count(format, "%s")
+	var verbs []string
+	for _, verb := range formatVerbRegex.FindAllString(format, -1) {
+		if verb != "%%" {
+			verbs = append(verbs, verb)
+		}
+	}
+	expectedMatchLen := len(verbs)
 
 	if !regex.MatchString(input) {
 		return input, nil
@@ -44,7 +55,16 @@ func ApplyRegex(input string, regex *regexp.Regexp, format string) (string, erro
 	captureGroups := matches[1:]
 	args := make([]interface{}, len(captureGroups))
 	for i, v := range captureGroups {
-		args[i] = v
+		if !strings.HasSuffix(verbs[i], "d") {
+			args[i] = v
+			continue
+		}
+
+		number, err := strconv.Atoi(v)
+		if err != nil {
+			return input, fmt.Errorf("capture group %d '%s' is not a number, as required by %s", i+1, v, verbs[i])
+		}
+		args[i] = number
 	}
 	newValue := fmt.Sprintf(format, args...)
 
@@ -63,44 +83,22 @@ func ReplaceDirAndExt(file string, dir string, ext string) string {
 	return path.Join(dir, fileName)
 }
 
-// NaturalCompare compares two strings using natural sort order (numbers are compared numerically).
-// For example: "file2.txt" < "file10.txt" (unlike lexicographic where "file10.txt" < "file2.txt")
-func NaturalCompare(a, b string) int {
-	ia, ib := 0, 0
-	for ia < len(a) && ib < len(b) {
-		// Check if both current characters are digits
-		if unicode.IsDigit(rune(a[ia])) && unicode.IsDigit(rune(b[ib])) {
-			// Extract full number from both strings
-			numStartA := ia
-			for ia < len(a) && unicode.IsDigit(rune(a[ia])) {
-				ia++
-			}
-			numStartB := ib
-			for ib < len(b) && unicode.IsDigit(rune(b[ib])) {
-				ib++
-			}
-
-			// Parse and compare numbers
-			numA, _ := strconv.Atoi(a[numStartA:ia])
-			numB, _ := strconv.Atoi(b[numStartB:ib])
+// TitleCase upper-cases the first letter of every word and lower-cases the rest.
+// Words are separated by spaces, hyphens and other non-letter characters,
+// e.g. "DIE DREI ??? und der super-papagei" becomes "Die Drei ??? Und Der Super-Papagei".
+func TitleCase(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
 
-			if numA != numB {
-				if numA < numB {
-					return -1
-				}
-				return 1
-			}
-			// Numbers are equal, continue comparing rest of string
+	startOfWord := true
+	for _, r := range s {
+		if startOfWord {
+			sb.WriteRune(unicode.ToUpper(r))
 		} else {
-			// Compare characters normally
-			if a[ia] != b[ib] {
-				return int(a[ia]) - int(b[ib])
-			}
-			ia++
-			ib++
+			sb.WriteRune(unicode.ToLower(r))
 		}
+		startOfWord = !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
 	}
 
-	// If one string is a prefix of the other
-	return len(a) - len(b)
+	return sb.String()
 }
@@ -30,6 +30,28 @@ func TestApplyRegex(t *testing.T) {
 			format:   "Folge %s_ %s",
 			expected: "Folge 213_ Something a little Longer.m4b",
 		},
+		{
+			name:     "number",
+			input:    "Chapter 7",
+			regex:    regexp.MustCompile(`^Chapter (\d+)$`),
+			format:   "Kapitel %02d",
+			expected: "Kapitel 07",
+		},
+		{
+			name:     "number and string",
+			input:    "3 - Der Fluch",
+			regex:    regexp.MustCompile(`^(\d+) - (.+)$`),
+			format:   "%03d: %s (100%%)",
+			expected: "003: Der Fluch (100%)",
+		},
+		{
+			name:     "not a number",
+			input:    "Chapter X",
+			regex:    regexp.MustCompile(`^Chapter (\w+)$`),
+			format:   "Kapitel %d",
+			expected: "Chapter X",
+			wantErr:  true,
+		},
 		{
 			name:     "no match",
 			input:    "01 213_Something a little Longer.m4b",
@@ -40,7 +62,10 @@ func TestApplyRegex(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, _ := ApplyRegex(tt.input, tt.regex, tt.format)
+			got, err := ApplyRegex(tt.input, tt.regex, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ApplyRegex() error = %v, wantErr %v", err, tt.wantErr)
+			}
 			if got != tt.expected {
 				t.Errorf("GetMetadataField() = %v, expected %v", got, tt.expected)
 			}
@@ -67,34 +92,20 @@ func TestSanitizePathComponent(t *testing.T) {
 	}
 }
 
-func TestNaturalCompare(t *testing.T) {
+func TestTitleCase(t *testing.T) {
 	tests := []struct {
-		name     string
-		a        string
-		b        string
-		expected int // negative if a < b, 0 if equal, positive if a > b
+		input    string
+		expected string
 	}{
-		{name: "numbers in order", a: "file2.txt", b: "file10.txt", expected: -1},
-		{name: "numbers reversed", a: "file10.txt", b: "file2.txt", expected: 1},
-		{name: "equal strings", a: "file10.txt", b: "file10.txt", expected: 0},
-		{name: "three digit numbers", a: "file100.txt", b: "file10.txt", expected: 1},
-		{name: "leading zeros", a: "file01.txt", b: "file1.txt", expected: 1}, // numerically equal but longer string
-		{name: "prefix comparison", a: "file10", b: "file10.txt", expected: -1},
-		{name: "multiple numbers", a: "01-file-10.txt", b: "02-file-9.txt", expected: -1},
-		{name: "chapter example", a: "10-kapitel.flac", b: "100-kapitel.flac", expected: -1},
-		{name: "chapter example 2", a: "09-kapitel.flac", b: "10-kapitel.flac", expected: -1},
+		{input: "DER HERR DER RINGE", expected: "Der Herr Der Ringe"},
+		{input: "die drei ??? und der super-papagei", expected: "Die Drei ??? Und Der Super-Papagei"},
+		{input: "öde ärger", expected: "Öde Ärger"},
+		{input: "o'brien's 2nd book", expected: "O'brien's 2nd Book"},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := NaturalCompare(tt.a, tt.b)
-			if tt.expected < 0 {
-				require.Less(t, result, 0, "expected %s < %s", tt.a, tt.b)
-			} else if tt.expected > 0 {
-				require.Greater(t, result, 0, "expected %s > %s", tt.a, tt.b)
-			} else {
-				require.Equal(t, 0, result, "expected %s == %s", tt.a, tt.b)
-			}
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expected, TitleCase(tt.input))
 		})
 	}
 }